@@ -0,0 +1,41 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/banners"
+	"github.com/MrHakimov/http/pkg/logging"
+)
+
+// SetAuthenticator sets how callers are identified, without it only public routes are allowed
+func (s *Server) SetAuthenticator(authenticator auth.Authenticator) {
+	s.authenticator = authenticator
+}
+
+// authorized lets to handler only callers having role
+func (s *Server) authorized(role auth.Role, handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		request, ok := s.authorize(writer, request, role)
+		if !ok {
+			return
+		}
+		handler(writer, request)
+	}
+}
+
+// authorize checks role of caller and writes 401 or 403 when it fails,
+// changes made by request and its log entries are attributed to authenticated principal
+func (s *Server) authorize(writer http.ResponseWriter, request *http.Request, role auth.Role) (*http.Request, bool) {
+	request, status := auth.Authorize(s.authenticator, role, request)
+	if status != http.StatusOK {
+		auth.Deny(writer, status)
+		return request, false
+	}
+	if principal := auth.FromContext(request.Context()); principal != nil {
+		ctx := banners.WithActor(request.Context(), principal.Name)
+		ctx = logging.WithContext(ctx, logger(request).With("actor", principal.Name))
+		request = request.WithContext(ctx)
+	}
+	return request, true
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// maxBatchOperations limits operations of one /banners.batch request
+const maxBatchOperations = 1000
+
+// batchRequest is body of /banners.batch
+type batchRequest struct {
+	// Atomic applies all operations or none of them, otherwise each one is applied on its own
+	Atomic     bool             `json:"atomic"`
+	Operations []batchOperation `json:"operations"`
+}
+
+// batchOperation creates banner, updates banner id of given revision or deletes banner id
+type batchOperation struct {
+	Action   banners.Action `json:"action"`
+	ID       int64          `json:"id,omitempty"`
+	Revision int64          `json:"revision,omitempty"`
+	Banner   *bannerRequest `json:"banner,omitempty"`
+}
+
+type batchResponse struct {
+	Atomic    bool                `json:"atomic"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Results   []batchItemResponse `json:"results"`
+}
+
+// batchItemResponse is outcome of operation, status is one /banners.save or /banners.removeById
+// would answer, 424 marks operation skipped because other one of atomic batch failed
+type batchItemResponse struct {
+	Action banners.Action       `json:"action"`
+	ID     int64                `json:"id,omitempty"`
+	Status int                  `json:"status"`
+	Banner *bannerResponse      `json:"banner,omitempty"`
+	Error  string               `json:"error,omitempty"`
+	Errors []banners.FieldError `json:"errors,omitempty"`
+}
+
+func (s *Server) handleBatchBanners(writer http.ResponseWriter, request *http.Request) {
+	var input batchRequest
+	if !decodeJSON(writer, request, &input) {
+		return
+	}
+
+	ops, verr := batchOps(input.Operations)
+	if verr != nil {
+		logger(request).Debug("invalid request", "error", verr)
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	}
+
+	results, err := s.bannersSvc.Batch(request.Context(), ops, input.Atomic)
+	if err != nil && !errors.Is(err, banners.ErrBatchFailed) {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	response := batchResponse{Atomic: input.Atomic, Results: make([]batchItemResponse, len(results))}
+	for i, result := range results {
+		item := s.batchItemResponse(request, ops[i], result, err != nil)
+		if item.Banner != nil {
+			response.Succeeded++
+		} else if item.Status != http.StatusFailedDependency {
+			response.Failed++
+		}
+		response.Results[i] = item
+	}
+
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err, "failed", response.Failed)
+		respond(writer, request, http.StatusBadRequest, response, nil)
+		return
+	}
+	respond(writer, request, http.StatusOK, response, nil)
+}
+
+// batchOps converts operations of request, shape of every one is checked before any is applied
+func batchOps(operations []batchOperation) ([]banners.BatchOp, *banners.ValidationError) {
+	verr := &banners.ValidationError{}
+	if len(operations) == 0 {
+		verr.Errors = append(verr.Errors, banners.FieldError{Field: "operations", Message: "is required"})
+	}
+	if len(operations) > maxBatchOperations {
+		verr.Errors = append(verr.Errors, banners.FieldError{
+			Field: "operations", Message: "must have at most " + strconv.Itoa(maxBatchOperations) + " items",
+		})
+	}
+
+	ops := make([]banners.BatchOp, len(operations))
+	for i, operation := range operations {
+		field := "operations[" + strconv.Itoa(i) + "]."
+		ops[i] = banners.BatchOp{Action: operation.Action, ID: operation.ID}
+		switch operation.Action {
+		case banners.ActionCreate, banners.ActionUpdate:
+			if operation.Banner == nil {
+				verr.Errors = append(verr.Errors, banners.FieldError{Field: field + "banner", Message: "is required"})
+				continue
+			}
+			ops[i].Banner = operation.Banner.banner(operation.ID)
+			ops[i].Banner.Revision = operation.Revision
+			if operation.Action == banners.ActionCreate && operation.ID != 0 {
+				verr.Errors = append(verr.Errors, banners.FieldError{Field: field + "id", Message: "must be omitted to create"})
+			}
+			if operation.Action == banners.ActionUpdate && operation.ID <= 0 {
+				verr.Errors = append(verr.Errors, banners.FieldError{Field: field + "id", Message: "is required"})
+			}
+			if operation.Action == banners.ActionUpdate && operation.Revision <= 0 {
+				verr.Errors = append(verr.Errors, banners.FieldError{Field: field + "revision", Message: "is required"})
+			}
+		case banners.ActionDelete:
+			if operation.ID <= 0 {
+				verr.Errors = append(verr.Errors, banners.FieldError{Field: field + "id", Message: "is required"})
+			}
+		default:
+			verr.Errors = append(verr.Errors, banners.FieldError{
+				Field: field + "action", Message: "must be one of create, update, delete",
+			})
+		}
+	}
+	if len(verr.Errors) != 0 {
+		return nil, verr
+	}
+	return ops, nil
+}
+
+// batchItemResponse describes result of op, aborted is set when atomic batch was not applied
+func (s *Server) batchItemResponse(request *http.Request, op banners.BatchOp, result banners.BatchResult, aborted bool) batchItemResponse {
+	item := batchItemResponse{Action: op.Action, ID: op.ID}
+	var verr *banners.ValidationError
+	switch {
+	case result.Err == nil && aborted:
+		item.Status = http.StatusFailedDependency
+		item.Error = "not applied because other operation failed"
+		return item
+	case result.Err == nil:
+		item.Status = http.StatusOK
+		if op.Action == banners.ActionCreate {
+			item.Status = http.StatusCreated
+		}
+		banner := s.bannerResponse(result.Banner)
+		item.ID = banner.ID
+		item.Banner = &banner
+		return item
+	case errors.As(result.Err, &verr):
+		item.Status = http.StatusBadRequest
+		item.Errors = verr.Errors
+	case result.Err == banners.ErrNotFound:
+		item.Status = http.StatusNotFound
+	case result.Err == banners.ErrConflict:
+		item.Status = http.StatusPreconditionFailed
+	case result.Err == banners.ErrBatchDuplicate:
+		item.Status = http.StatusConflict
+	default:
+		logger(request).Error("batch operation failed", "action", op.Action, "banner_id", op.ID, "error", result.Err)
+		item.Status = http.StatusInternalServerError
+		item.Error = http.StatusText(http.StatusInternalServerError)
+		return item
+	}
+	item.Error = result.Err.Error()
+	return item
+}
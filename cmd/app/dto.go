@@ -0,0 +1,334 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// envelopeMediaType is asked in Accept header (or by envelope=true parameter)
+// to get response as {"data": ..., "meta": ...}
+const envelopeMediaType = "application/vnd.banners.envelope+json"
+
+// envelope wraps data together with metadata
+type envelope struct {
+	Data interface{}            `json:"data"`
+	Meta map[string]interface{} `json:"meta"`
+}
+
+// bannerRequest is writable part of banner accepted as JSON (and as JSON merge-patch target)
+type bannerRequest struct {
+	Title     string            `json:"title"`
+	Content   string            `json:"content"`
+	Button    string            `json:"button"`
+	Link      string            `json:"link"`
+	Status    banners.Status    `json:"status"`
+	StartsAt  *time.Time        `json:"starts_at,omitempty"`
+	EndsAt    *time.Time        `json:"ends_at,omitempty"`
+	Priority  int               `json:"priority"`
+	Weight    int               `json:"weight"`
+	Targeting *targetingPayload `json:"targeting,omitempty"`
+}
+
+type targetingPayload struct {
+	Locales    []string          `json:"locales,omitempty"`
+	Devices    []banners.Device  `json:"devices,omitempty"`
+	PathPrefix string            `json:"path_prefix,omitempty"`
+	Segments   map[string]string `json:"segments,omitempty"`
+}
+
+type bannerResponse struct {
+	ID        int64             `json:"id"`
+	Title     string            `json:"title"`
+	Content   string            `json:"content"`
+	Button    string            `json:"button,omitempty"`
+	Link      string            `json:"link,omitempty"`
+	Image     *imageResponse    `json:"image,omitempty"`
+	Status    banners.Status    `json:"status"`
+	StartsAt  string            `json:"starts_at,omitempty"`
+	EndsAt    string            `json:"ends_at,omitempty"`
+	Priority  int               `json:"priority"`
+	Weight    int               `json:"weight,omitempty"`
+	Targeting *targetingPayload `json:"targeting,omitempty"`
+	Revision  int64             `json:"revision"`
+	DeletedAt string            `json:"deleted_at,omitempty"`
+}
+
+type imageResponse struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// Variants maps width to url of resized copy
+	Variants map[string]string `json:"variants,omitempty"`
+}
+
+type experimentResponse struct {
+	ID        int64                   `json:"id"`
+	Name      string                  `json:"name"`
+	Running   bool                    `json:"running"`
+	Variants  []variantResponse       `json:"variants"`
+	StartedAt string                  `json:"started_at,omitempty"`
+	Results   []banners.VariantResult `json:"results,omitempty"`
+}
+
+type variantResponse struct {
+	BannerID int64 `json:"banner_id"`
+	Weight   int   `json:"weight"`
+}
+
+type versionResponse struct {
+	Version  int              `json:"version"`
+	Action   banners.Action   `json:"action"`
+	Actor    string           `json:"actor"`
+	At       string           `json:"at"`
+	Changes  []changeResponse `json:"changes"`
+	Snapshot bannerResponse   `json:"snapshot"`
+}
+
+type changeResponse struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type statsResponse struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Banners []banners.Stats `json:"banners"`
+}
+
+func requestFromBanner(item *banners.Banner) *bannerRequest {
+	request := &bannerRequest{
+		Title:     item.Title,
+		Content:   item.Content,
+		Button:    item.Button,
+		Link:      item.Link,
+		Status:    item.Status,
+		Priority:  item.Priority,
+		Weight:    item.Weight,
+		Targeting: targetingFromBanner(item),
+	}
+	if !item.StartsAt.IsZero() {
+		request.StartsAt = &item.StartsAt
+	}
+	if !item.EndsAt.IsZero() {
+		request.EndsAt = &item.EndsAt
+	}
+	return request
+}
+
+func (in *bannerRequest) banner(id int64) *banners.Banner {
+	item := &banners.Banner{
+		ID:       id,
+		Title:    in.Title,
+		Content:  in.Content,
+		Button:   in.Button,
+		Link:     in.Link,
+		Status:   in.Status,
+		Priority: in.Priority,
+		Weight:   in.Weight,
+	}
+	if in.StartsAt != nil {
+		item.StartsAt = *in.StartsAt
+	}
+	if in.EndsAt != nil {
+		item.EndsAt = *in.EndsAt
+	}
+	if in.Targeting != nil {
+		item.Targeting = banners.Targeting{
+			Locales:    in.Targeting.Locales,
+			Devices:    in.Targeting.Devices,
+			PathPrefix: in.Targeting.PathPrefix,
+			Segments:   in.Targeting.Segments,
+		}
+	}
+	return item
+}
+
+func targetingFromBanner(item *banners.Banner) *targetingPayload {
+	targeting := item.Targeting
+	if len(targeting.Locales) == 0 && len(targeting.Devices) == 0 &&
+		targeting.PathPrefix == "" && len(targeting.Segments) == 0 {
+		return nil
+	}
+	return &targetingPayload{
+		Locales:    targeting.Locales,
+		Devices:    targeting.Devices,
+		PathPrefix: targeting.PathPrefix,
+		Segments:   targeting.Segments,
+	}
+}
+
+func (s *Server) bannerResponse(item *banners.Banner) bannerResponse {
+	response := bannerResponse{
+		ID:        item.ID,
+		Title:     item.Title,
+		Content:   item.Content,
+		Button:    item.Button,
+		Link:      item.Link,
+		Status:    item.Status,
+		StartsAt:  formatTime(item.StartsAt),
+		EndsAt:    formatTime(item.EndsAt),
+		Priority:  item.Priority,
+		Weight:    item.Weight,
+		Targeting: targetingFromBanner(item),
+		Revision:  item.Revision,
+		DeletedAt: formatTime(item.DeletedAt),
+	}
+
+	if item.Image != "" {
+		store := s.bannersSvc.Store()
+		response.Image = &imageResponse{URL: store.URL(item.Image), Width: item.Width, Height: item.Height}
+		if len(item.Variants) != 0 {
+			response.Image.Variants = make(map[string]string, len(item.Variants))
+			for width, key := range item.Variants {
+				response.Image.Variants[strconv.Itoa(width)] = store.URL(key)
+			}
+		}
+	}
+	return response
+}
+
+func (s *Server) bannersResponse(items []*banners.Banner) []bannerResponse {
+	response := make([]bannerResponse, len(items))
+	for i, item := range items {
+		response[i] = s.bannerResponse(item)
+	}
+	return response
+}
+
+func experimentFromEntity(experiment *banners.Experiment) experimentResponse {
+	response := experimentResponse{
+		ID:        experiment.ID,
+		Name:      experiment.Name,
+		Running:   experiment.Running,
+		Variants:  make([]variantResponse, len(experiment.Variants)),
+		StartedAt: formatTime(experiment.StartedAt),
+	}
+	for i, variant := range experiment.Variants {
+		response.Variants[i] = variantResponse{BannerID: variant.BannerID, Weight: variant.Weight}
+	}
+	return response
+}
+
+func experimentsFromEntities(items []*banners.Experiment) []experimentResponse {
+	response := make([]experimentResponse, len(items))
+	for i, item := range items {
+		response[i] = experimentFromEntity(item)
+	}
+	return response
+}
+
+// versionsResponse maps versions of banner, oldest first, changed image is answered
+// like image of banner response
+func (s *Server) versionsResponse(versions []*banners.Version) []versionResponse {
+	response := make([]versionResponse, len(versions))
+	var previous *bannerResponse
+	for i, version := range versions {
+		snapshot := s.bannerResponse(&version.Snapshot)
+		changes := make([]changeResponse, len(version.Changes))
+		for j, change := range version.Changes {
+			changes[j] = changeResponse{
+				Field:  snakeCase(change.Field),
+				Before: changeValue(change.Before),
+				After:  changeValue(change.After),
+			}
+			if change.Field == "Image" {
+				changes[j].Before, changes[j].After = nil, snapshot.Image
+				if previous != nil {
+					changes[j].Before = previous.Image
+				}
+			}
+		}
+		response[i] = versionResponse{
+			Version:  version.Version,
+			Action:   version.Action,
+			Actor:    version.Actor,
+			At:       formatTime(version.At),
+			Changes:  changes,
+			Snapshot: snapshot,
+		}
+		previous = &response[i].Snapshot
+	}
+	return response
+}
+
+// changeValue converts field value of banner to its API representation
+func changeValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case time.Time:
+		return formatTime(value)
+	case banners.Targeting:
+		return targetingFromBanner(&banners.Banner{Targeting: value})
+	}
+	return value
+}
+
+// formatTime formats time as RFC 3339 in UTC, zero time becomes empty string
+func formatTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.UTC().Format(time.RFC3339)
+}
+
+// snakeCase turns Go field name like StartsAt into starts_at
+func snakeCase(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// wantsEnvelope reports whether client negotiated wrapped response
+func wantsEnvelope(request *http.Request) bool {
+	if envelope, err := strconv.ParseBool(request.URL.Query().Get("envelope")); err == nil {
+		return envelope
+	}
+	for _, accepted := range strings.Split(request.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.SplitN(accepted, ";", 2)[0]) == envelopeMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// respond writes data as JSON, wrapped with meta into envelope when client asks for it
+func respond(writer http.ResponseWriter, request *http.Request, status int, data interface{}, meta map[string]interface{}) {
+	writer.Header().Add("Vary", "Accept")
+	if !wantsEnvelope(request) {
+		writeJSON(writer, status, data)
+		return
+	}
+
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+	meta["generated_at"] = formatTime(time.Now())
+
+	body, err := json.Marshal(envelope{Data: data, Meta: meta})
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", envelopeMediaType)
+	writer.WriteHeader(status)
+	_, err = writer.Write(body)
+	if err != nil {
+		logger(request).Warn("write response", "error", err)
+	}
+}
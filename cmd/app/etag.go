@@ -0,0 +1,55 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// etag of banner is its quoted revision
+func etag(item *banners.Banner) string {
+	return `"` + strconv.FormatInt(item.Revision, 10) + `"`
+}
+
+// matchETag reports whether If-None-Match header lists tag or "*",
+// comparison is weak (RFC 7232, section 3.2), so W/"1" matches "1"
+func matchETag(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStrongETag reports whether If-Match header lists tag or "*",
+// comparison is strong (RFC 7232, section 3.1), so weak tags never match
+func matchStrongETag(header string, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch puts current revision into banner when required If-Match header lists its tag,
+// it returns 428 when header is missing and 412 when banner does not exist or tag is not listed;
+// Save fails with ErrConflict when banner changes in between
+func (s *Server) checkIfMatch(request *http.Request, banner *banners.Banner) int {
+	header := strings.TrimSpace(request.Header.Get("If-Match"))
+	if header == "" {
+		return http.StatusPreconditionRequired
+	}
+
+	current, err := s.bannersSvc.ByID(request.Context(), banner.ID)
+	if err != nil || !matchStrongETag(header, etag(current)) {
+		return http.StatusPreconditionFailed
+	}
+	banner.Revision = current.Revision
+	return http.StatusOK
+}
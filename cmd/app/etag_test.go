@@ -0,0 +1,141 @@
+package app
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMatchETag(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		weak   bool
+		strong bool
+	}{
+		{"same", `"2"`, true, true},
+		{"other", `"1"`, false, false},
+		{"any", `*`, true, true},
+		{"weak", `W/"2"`, true, false},
+		{"list", `"1", "2"`, true, true},
+		{"list without spaces", `"1","3","2"`, true, true},
+		{"weak in list", `"1", W/"2"`, true, false},
+		{"unquoted", `2`, false, false},
+		{"empty", ``, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := matchETag(test.header, `"2"`); got != test.weak {
+				t.Errorf("matchETag(%q) = %v, want %v", test.header, got, test.weak)
+			}
+			if got := matchStrongETag(test.header, `"2"`); got != test.strong {
+				t.Errorf("matchStrongETag(%q) = %v, want %v", test.header, got, test.strong)
+			}
+		})
+	}
+}
+
+func TestSaveIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+	}{
+		{"missing", "", http.StatusPreconditionRequired},
+		{"current", `"1"`, http.StatusOK},
+		{"stale", `"0"`, http.StatusPreconditionFailed},
+		{"weak current", `W/"1"`, http.StatusPreconditionFailed},
+		{"list with current", `"7", "1"`, http.StatusOK},
+		{"list without current", `"7", W/"1"`, http.StatusPreconditionFailed},
+		{"any", `*`, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, svc := testServer(t)
+			item := testBanner(t, svc)
+			id := strconv.FormatInt(item.ID, 10)
+			header := http.Header{}
+			if test.ifMatch != "" {
+				header.Set("If-Match", test.ifMatch)
+			}
+
+			form := url.Values{"id": {id}, "title": {"Sale"}, "content": {"Changed"}}
+			header.Set("Content-Type", "application/x-www-form-urlencoded")
+			response := testRequest(server, http.MethodPost, "/banners.save", strings.NewReader(form.Encode()), header)
+			if response.Code != test.status {
+				t.Fatalf("save status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if test.status == http.StatusOK && response.Header().Get("ETag") != `"2"` {
+				t.Errorf("ETag = %q, want revision 2", response.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestDeleteIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+	}{
+		{"missing", "", http.StatusNoContent},
+		{"current", `"1"`, http.StatusNoContent},
+		{"stale", `"0"`, http.StatusPreconditionFailed},
+		{"weak current", `W/"1"`, http.StatusPreconditionFailed},
+		{"list with current", `"7", "1"`, http.StatusNoContent},
+		{"any", `*`, http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, svc := testServer(t)
+			item := testBanner(t, svc)
+			header := http.Header{}
+			if test.ifMatch != "" {
+				header.Set("If-Match", test.ifMatch)
+			}
+
+			response := testRequest(server, http.MethodDelete, bannersPath+"/"+strconv.FormatInt(item.ID, 10), nil, header)
+			if response.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+		})
+	}
+}
+
+func TestRollbackIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		status  int
+	}{
+		{"missing", "", http.StatusPreconditionRequired},
+		{"current", `"1"`, http.StatusOK},
+		{"stale", `"0"`, http.StatusPreconditionFailed},
+		{"any", `*`, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, svc := testServer(t)
+			item := testBanner(t, svc)
+			header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+			if test.ifMatch != "" {
+				header.Set("If-Match", test.ifMatch)
+			}
+
+			form := url.Values{"id": {strconv.FormatInt(item.ID, 10)}, "version": {"1"}}
+			response := testRequest(server, http.MethodPost, "/banners.rollback", strings.NewReader(form.Encode()), header)
+			if response.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if test.status == http.StatusOK && response.Header().Get("ETag") != `"2"` {
+				t.Errorf("ETag = %q, want revision 2", response.Header().Get("ETag"))
+			}
+		})
+	}
+}
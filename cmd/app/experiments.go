@@ -0,0 +1,224 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// visitorCookie keeps random visitor id, so experiment assignment is sticky
+const visitorCookie = "banners_visitor"
+
+func (s *Server) handleGetAllExperiments(writer http.ResponseWriter, request *http.Request) {
+	items, err := s.experiments.All(request.Context())
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respond(writer, request, http.StatusOK, experimentsFromEntities(items), map[string]interface{}{"count": len(items)})
+}
+
+func (s *Server) handleGetExperimentById(writer http.ResponseWriter, request *http.Request) {
+	experiment, ok := s.experimentByRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	respond(writer, request, http.StatusOK, experimentFromEntity(experiment), nil)
+}
+
+// handleSaveExperiment takes id, name, running (true/false)
+// and variants as list of banner_id:weight, e.g. "1:50,2:50"
+func (s *Server) handleSaveExperiment(writer http.ResponseWriter, request *http.Request) {
+	idParam := request.PostFormValue("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	formErr := &banners.ValidationError{}
+	variants, err := parseVariants(request.FormValue("variants"))
+	if err != nil {
+		formErr.Errors = append(formErr.Errors, banners.FieldError{Field: "variants", Message: "must be list of banner_id:weight"})
+	}
+	running := false
+	if raw := request.FormValue("running"); raw != "" {
+		running, err = strconv.ParseBool(raw)
+		if err != nil {
+			formErr.Errors = append(formErr.Errors, banners.FieldError{Field: "running", Message: "must be boolean"})
+		}
+	}
+	if len(formErr.Errors) != 0 {
+		logger(request).Debug("invalid request", "error", formErr)
+		writeJSON(writer, http.StatusBadRequest, formErr)
+		return
+	}
+
+	item, err := s.experiments.Save(request.Context(), &banners.Experiment{
+		ID:       id,
+		Name:     request.FormValue("name"),
+		Running:  running,
+		Variants: variants,
+	})
+	var verr *banners.ValidationError
+	if errors.As(err, &verr) {
+		logger(request).Debug("invalid request", "error", err)
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	}
+	if err == banners.ErrExperimentNotFound {
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respond(writer, request, http.StatusOK, experimentFromEntity(item), nil)
+}
+
+func (s *Server) handleRemoveExperimentById(writer http.ResponseWriter, request *http.Request) {
+	idParam := request.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.experiments.RemoveByID(request.Context(), id)
+	if err == banners.ErrExperimentNotFound {
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respond(writer, request, http.StatusOK, experimentFromEntity(item), nil)
+}
+
+// handleAssignExperiment returns banner of variant assigned to visitor,
+// visitor is identified by visitor parameter or by cookie which is set on first visit
+func (s *Server) handleAssignExperiment(writer http.ResponseWriter, request *http.Request) {
+	experiment, ok := s.experimentByRequest(writer, request)
+	if !ok {
+		return
+	}
+	if !experiment.Running {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	visitorID := request.URL.Query().Get("visitor")
+	if visitorID == "" {
+		if cookie, err := request.Cookie(visitorCookie); err == nil && cookie.Value != "" {
+			visitorID = cookie.Value
+		} else {
+			visitorID, err = newVisitorID()
+			if err != nil {
+				logger(request).Error("request failed", "error", err)
+				http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(writer, &http.Cookie{
+				Name:     visitorCookie,
+				Value:    visitorID,
+				Path:     "/",
+				Expires:  time.Now().AddDate(1, 0, 0),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+
+	variant := experiment.Assign(visitorID)
+	item, err := s.bannersSvc.ByID(request.Context(), variant.BannerID)
+	if errors.Is(err, banners.ErrNotFound) {
+		// banner of variant was removed after experiment was saved
+		logger(request).Debug("banner not found", "id", variant.BannerID)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Cache-Control", "private, no-store")
+	respond(writer, request, http.StatusOK, s.bannerResponse(item), nil)
+}
+
+func (s *Server) handleExperimentResults(writer http.ResponseWriter, request *http.Request) {
+	experiment, ok := s.experimentByRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	response := experimentFromEntity(experiment)
+	response.Results = experiment.Results(request.Context(), s.tracker, time.Now())
+	respond(writer, request, http.StatusOK, response, nil)
+}
+
+// experimentByRequest looks for experiment by id parameter and writes error response when it fails
+func (s *Server) experimentByRequest(writer http.ResponseWriter, request *http.Request) (*banners.Experiment, bool) {
+	idParam := request.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, false
+	}
+
+	experiment, err := s.experiments.ByID(request.Context(), id)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, false
+	}
+	return experiment, true
+}
+
+func newVisitorID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// parseVariants reads list like "1:50,2:50", weight is 1 when omitted
+func parseVariants(value string) ([]banners.ExperimentVariant, error) {
+	variants := make([]banners.ExperimentVariant, 0)
+	for _, part := range splitList(value) {
+		fields := strings.SplitN(part, ":", 2)
+		id, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		weight := 1
+		if len(fields) == 2 {
+			weight, err = strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, err
+			}
+		}
+		variants = append(variants, banners.ExperimentVariant{BannerID: id, Weight: weight})
+	}
+	return variants, nil
+}
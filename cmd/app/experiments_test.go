@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+func TestExperimentEndpoints(t *testing.T) {
+	server, svc := testServer(t)
+	ctx := context.Background()
+	control, removed := testBanner(t, svc), testBanner(t, svc)
+	experiment, err := server.experiments.Save(ctx, &banners.Experiment{Name: "Title test", Running: true, Variants: []banners.ExperimentVariant{
+		{BannerID: control.ID, Weight: 50}, {BannerID: removed.ID, Weight: 50},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.RemoveByID(ctx, removed.ID); err != nil {
+		t.Fatal(err)
+	}
+	// clicks are counted apart from impressions, so they may outnumber them
+	server.tracker.RecordImpression(ctx, control.ID)
+	for i := 0; i < 3; i++ {
+		server.tracker.RecordClick(ctx, control.ID)
+	}
+	visitorOf := func(bannerID int64) string {
+		for i := 0; ; i++ {
+			visitor := "visitor-" + strconv.Itoa(i)
+			if experiment.Assign(visitor).BannerID == bannerID {
+				return visitor
+			}
+		}
+	}
+	id := strconv.FormatInt(experiment.ID, 10)
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+	}{
+		{"assign live variant", "/experiments.assign?id=" + id + "&visitor=" + visitorOf(control.ID), http.StatusOK},
+		{"assign removed variant", "/experiments.assign?id=" + id + "&visitor=" + visitorOf(removed.ID), http.StatusNotFound},
+		{"assign missing experiment", "/experiments.assign?id=999&visitor=a", http.StatusNotFound},
+		{"results with more clicks than impressions", "/experiments.results?id=" + id, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := testRequest(server, http.MethodGet, test.target, nil, nil)
+			if response.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+		})
+	}
+
+	var results struct {
+		Results []banners.VariantResult `json:"results"`
+	}
+	response := testRequest(server, http.MethodGet, "/experiments.results?id="+id, nil, nil)
+	if err := json.Unmarshal(response.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Results) != 2 {
+		t.Fatalf("%d results, want 2: %s", len(results.Results), response.Body)
+	}
+	for _, result := range results.Results {
+		if math.IsNaN(result.ZScore) || result.PValue != 1 || result.Significant {
+			t.Errorf("result %+v, want insufficient data", result)
+		}
+	}
+}
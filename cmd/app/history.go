@@ -0,0 +1,106 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+func (s *Server) handleHistory(writer http.ResponseWriter, request *http.Request) {
+	idParam := request.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	versions, err := s.bannersSvc.History(request.Context(), id)
+	if err == banners.ErrNotFound {
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respond(writer, request, http.StatusOK, s.versionsResponse(versions), map[string]interface{}{"count": len(versions)})
+}
+
+func (s *Server) handleRollback(writer http.ResponseWriter, request *http.Request) {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.Atoi(request.FormValue("version"))
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	current := &banners.Banner{ID: id}
+	if status := s.checkIfMatch(request, current); status != http.StatusOK {
+		http.Error(writer, http.StatusText(status), status)
+		return
+	}
+
+	item, err := s.bannersSvc.Rollback(request.Context(), id, current.Revision, version)
+	s.writeBanner(writer, request, http.StatusOK, item, err)
+}
+
+// handleRestoreById does not require If-Match: deleted banner is not served, so client has no ETag of it,
+// and restore can not overwrite changes as banner in trash does not change and is restored only once
+func (s *Server) handleRestoreById(writer http.ResponseWriter, request *http.Request) {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.bannersSvc.Restore(request.Context(), id)
+	s.writeBanner(writer, request, http.StatusOK, item, err)
+}
+
+func (s *Server) handlePurgeById(writer http.ResponseWriter, request *http.Request) {
+	id, err := strconv.ParseInt(request.FormValue("id"), 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.bannersSvc.Purge(request.Context(), id)
+	s.writeBanner(writer, request, http.StatusOK, item, err)
+}
+
+// writeBanner writes banner with its ETag or maps error of banners.Service to status code
+func (s *Server) writeBanner(writer http.ResponseWriter, request *http.Request, status int, item *banners.Banner, err error) {
+	var verr *banners.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		logger(request).Debug("invalid request", "error", err)
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	case err == banners.ErrNotFound || err == banners.ErrVersionNotFound:
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	case err == banners.ErrConflict:
+		http.Error(writer, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+		return
+	case err != nil:
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("ETag", etag(item))
+	respond(writer, request, status, s.bannerResponse(item), nil)
+}
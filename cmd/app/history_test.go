@@ -0,0 +1,105 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// imageFile is uploaded image of multipart form
+type imageFile struct {
+	*bytes.Reader
+}
+
+func (imageFile) Close() error {
+	return nil
+}
+
+func pngFile(t *testing.T, width, height int) imageFile {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return imageFile{bytes.NewReader(buf.Bytes())}
+}
+
+func TestHistoryChanges(t *testing.T) {
+	server, svc := testServer(t)
+	ctx := context.Background()
+	item, err := svc.Save(ctx, &banners.Banner{Title: "Sale", Content: "Half price"}, pngFile(t, 700, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	item = &banners.Banner{ID: item.ID, Title: "Big sale", Content: item.Content, Revision: item.Revision}
+	if item, err = svc.Save(ctx, item, nil); err != nil {
+		t.Fatal(err)
+	}
+	item = &banners.Banner{ID: item.ID, Title: item.Title, Content: item.Content, Revision: item.Revision}
+	if _, err = svc.Save(ctx, item, pngFile(t, 1400, 20)); err != nil {
+		t.Fatal(err)
+	}
+
+	response := testRequest(server, http.MethodGet, "/banners.history?id="+strconv.FormatInt(item.ID, 10), nil, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", response.Code, response.Body)
+	}
+	var versions []struct {
+		Changes []struct {
+			Field  string      `json:"field"`
+			Before interface{} `json:"before"`
+			After  interface{} `json:"after"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &versions); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+		// image is whether image change has urls before and after
+		before, after bool
+	}{
+		{"created", []string{"title", "content", "image", "status"}, false, true},
+		{"title changed", []string{"title"}, false, false},
+		{"image replaced", []string{"image"}, true, true},
+	}
+	if len(versions) != len(tests) {
+		t.Fatalf("%d versions, want %d", len(versions), len(tests))
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fields []string
+			for _, change := range versions[i].Changes {
+				fields = append(fields, change.Field)
+				if change.Field != "image" {
+					continue
+				}
+				for _, side := range []struct {
+					value interface{}
+					want  bool
+				}{{change.Before, test.before}, {change.After, test.after}} {
+					img, _ := side.value.(map[string]interface{})
+					url, _ := img["url"].(string)
+					if side.want != strings.HasPrefix(url, "http://cdn/") {
+						t.Errorf("image change %v, want url: %v", side.value, side.want)
+					}
+				}
+			}
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("changed fields = %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/MrHakimov/http/pkg/logging"
+)
+
+// RequestIDHeader carries request ID given by client or generated by server
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits request ID accepted from client
+const maxRequestIDLength = 128
+
+// SetLogger sets logger of requests, logging.Default is used without it
+func (s *Server) SetLogger(logger *logging.Logger) {
+	s.logger = logger
+}
+
+// withRequestLogger answers request ID and puts logger carrying it to context of request
+func (s *Server) withRequestLogger(writer http.ResponseWriter, request *http.Request) *http.Request {
+	id := requestID(request)
+	writer.Header().Set(RequestIDHeader, id)
+
+	base := s.logger
+	if base == nil {
+		base = logging.Default()
+	}
+	return request.WithContext(logging.WithContext(request.Context(), base.With("request_id", id)))
+}
+
+// requestID returns X-Request-ID of request when it is printable and not too long, otherwise new random ID
+func requestID(request *http.Request) string {
+	if id := request.Header.Get(RequestIDHeader); id != "" && len(id) <= maxRequestIDLength && printable(id) {
+		return id
+	}
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		logging.FromContext(request.Context()).Error("generate request id", "error", err)
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
+
+func printable(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] <= ' ' || value[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// logger returns logger of request carrying its ID
+func logger(request *http.Request) *logging.Logger {
+	return logging.FromContext(request.Context())
+}
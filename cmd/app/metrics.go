@@ -0,0 +1,126 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/metrics"
+)
+
+// httpMetrics instruments requests by mux pattern, method and status
+type httpMetrics struct {
+	requests *metrics.Counter
+	duration *metrics.Histogram
+	inFlight *metrics.Gauge
+	bytesIn  *metrics.Counter
+	bytesOut *metrics.Counter
+}
+
+func newHTTPMetrics(registry *metrics.Registry) *httpMetrics {
+	return &httpMetrics{
+		requests: registry.NewCounter("http_requests_total",
+			"Requests by route, method and status.", "route", "method", "status"),
+		duration: registry.NewHistogram("http_request_duration_seconds",
+			"Request latency by route, method and status.", metrics.DefaultBuckets, "route", "method", "status"),
+		inFlight: registry.NewGauge("http_requests_in_flight",
+			"Requests being served."),
+		bytesIn: registry.NewCounter("http_request_bytes_total",
+			"Bytes of request bodies by route.", "route"),
+		bytesOut: registry.NewCounter("http_response_bytes_total",
+			"Bytes of response bodies by route.", "route"),
+	}
+}
+
+// Metrics returns registry of server, banners service and requests metrics
+func (s *Server) Metrics() *metrics.Registry {
+	return s.metrics
+}
+
+// observe serves request by next, records its metrics and writes access log line
+func (s *Server) observe(writer http.ResponseWriter, request *http.Request, next http.Handler) {
+	_, route := s.mux.Handler(request)
+	if route == "" {
+		route = "unmatched"
+	}
+	method := request.Method
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+	default:
+		method = "other"
+	}
+
+	m := s.httpMetrics
+	m.inFlight.Inc()
+	defer m.inFlight.Dec()
+
+	body := &countingReader{ReadCloser: request.Body}
+	request.Body = body
+	recorder := &responseRecorder{ResponseWriter: writer}
+	started := time.Now()
+	next.ServeHTTP(recorder, request)
+
+	elapsed := time.Since(started)
+
+	status := strconv.Itoa(recorder.Status())
+	m.requests.Inc(route, method, status)
+	m.duration.Observe(elapsed.Seconds(), route, method, status)
+	m.bytesIn.Add(float64(body.read), route)
+	m.bytesOut.Add(float64(recorder.written), route)
+
+	logger(request).Info("request",
+		"method", request.Method,
+		"path", request.URL.Path,
+		"route", route,
+		"status", recorder.Status(),
+		"duration_ms", float64(elapsed.Microseconds())/1000,
+		"bytes_in", body.read,
+		"bytes", recorder.written,
+		"remote", request.RemoteAddr,
+	)
+}
+
+// responseRecorder remembers status and size of response
+type responseRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.written += int64(n)
+	return n, err
+}
+
+// Status returns written status, 200 when handler wrote nothing
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// countingReader counts bytes read from request body
+type countingReader struct {
+	io.ReadCloser
+	read int64
+}
+
+func (r *countingReader) Read(data []byte) (int, error) {
+	n, err := r.ReadCloser.Read(data)
+	r.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,559 @@
+package app
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/banners"
+	"github.com/MrHakimov/http/pkg/health"
+)
+
+// openAPIPath is where OpenAPI 3 document of the server is served
+const openAPIPath = "/openapi.json"
+
+// operation documents one method of route
+type operation struct {
+	Summary    string
+	Parameters []parameter
+	// Form lists fields of form body, body is multipart when one of them is binary
+	Form []parameter
+	// Body is value whose type is JSON request body, BodyTypes are its media types,
+	// body is binary when BodyTypes are given without Body
+	Body      interface{}
+	BodyTypes []string
+	// Status is success status, 200 when 0
+	Status int
+	// Result is value whose type is JSON response body, nil when there is no body
+	Result interface{}
+	// ResultMedia are media types of non-JSON response body
+	ResultMedia []string
+	// Plain is set when Result is never wrapped into envelope
+	Plain bool
+	// FailStatus is error status answered with Result too, like 503 of readiness
+	FailStatus int
+	Errors     []int
+	// Role is required instead of role of route, handler must check it itself
+	Role auth.Role
+}
+
+// parameter is query, header or form field, Type is OpenAPI type or "binary" for files
+type parameter struct {
+	Name        string
+	In          string
+	Type        string
+	Description string
+	Required    bool
+}
+
+var (
+	bannerIDQuery     = parameter{Name: "id", In: "query", Type: "integer", Description: "banner id", Required: true}
+	bannerIDField     = parameter{Name: "id", Type: "integer", Description: "banner id", Required: true}
+	experimentIDQuery = parameter{Name: "id", In: "query", Type: "integer", Description: "experiment id", Required: true}
+	ifMatchHeader     = parameter{Name: "If-Match", In: "header", Type: "string", Description: "ETag of banner being changed, * for any"}
+	ifNoneMatch       = parameter{Name: "If-None-Match", In: "header", Type: "string", Description: "ETag known to client"}
+	envelopeParam     = parameter{Name: "envelope", In: "query", Type: "boolean", Description: "wrap response into {data, meta}"}
+)
+
+// bannerForm are fields read by bannerFromForm
+var bannerForm = []parameter{
+	{Name: "title", Type: "string", Required: true},
+	{Name: "content", Type: "string", Required: true},
+	{Name: "button", Type: "string", Description: "required when link is set"},
+	{Name: "link", Type: "string", Description: "absolute http(s) url"},
+	{Name: "status", Type: "string", Description: "draft, published or archived"},
+	{Name: "priority", Type: "integer"},
+	{Name: "starts_at", Type: "string", Description: "RFC 3339 time"},
+	{Name: "ends_at", Type: "string", Description: "RFC 3339 time"},
+	{Name: "locales", Type: "string", Description: "comma separated list"},
+	{Name: "devices", Type: "string", Description: "comma separated list of desktop, mobile, tablet"},
+	{Name: "path_prefix", Type: "string"},
+	{Name: "segments", Type: "string", Description: "comma separated list of key:value"},
+	{Name: "weight", Type: "integer"},
+	{Name: "image", Type: "binary", Description: "png, jpeg or gif"},
+}
+
+// apiPaths documents every route registered in Init by its ServeMux pattern
+var apiPaths = map[string]map[string]operation{
+	"/banners.getAll": {
+		http.MethodGet: {Summary: "List banners", Parameters: []parameter{envelopeParam},
+			Result: []bannerResponse{}, Errors: []int{500}},
+	},
+	"/banners.getById": {
+		http.MethodGet: {Summary: "Get banner", Parameters: []parameter{bannerIDQuery, ifNoneMatch, envelopeParam},
+			Result: bannerResponse{}, Errors: []int{304, 400, 404, 500}},
+	},
+	"/banners.getActive": {
+		http.MethodGet: {Summary: "List banners active now by priority", Parameters: []parameter{envelopeParam},
+			Result: []bannerResponse{}, Errors: []int{500}},
+	},
+	"/banners.serve": {
+		http.MethodGet: {Summary: "Pick banner for visitor", Parameters: []parameter{
+			{Name: "path", In: "query", Type: "string", Description: "page where banner is shown"},
+			{Name: "segment", In: "query", Type: "string", Description: "key:value, may be repeated"},
+			{Name: "rotation", In: "query", Type: "string", Description: "weighted or round_robin"},
+			{Name: "seed", In: "query", Type: "integer", Description: "makes weighted pick deterministic"},
+			{Name: "Accept-Language", In: "header", Type: "string"},
+			envelopeParam,
+		}, Result: bannerResponse{}, Errors: []int{204, 400, 500}},
+	},
+	"/banners.save": {
+		http.MethodPost: {Summary: "Create (id is 0) or update banner", Parameters: []parameter{ifMatchHeader, envelopeParam},
+			Form: append([]parameter{bannerIDField}, bannerForm...), Result: bannerResponse{},
+			Errors: []int{400, 404, 412, 428, 500}},
+	},
+	"/banners.removeById": {
+		http.MethodPost: {Summary: "Soft delete banner", Parameters: []parameter{bannerIDQuery, envelopeParam},
+			Result: bannerResponse{}, Errors: []int{400, 404, 500}},
+	},
+	"/banners.image": {
+		http.MethodGet: {Summary: "Get banner image", Parameters: []parameter{bannerIDQuery,
+			{Name: "w", In: "query", Type: "integer", Description: "width of display, best variant is returned"},
+			{Name: "dpr", In: "query", Type: "number", Description: "device pixel ratio from 1 to 4 scaling w"},
+		}, ResultMedia: []string{"image/*"}, Errors: []int{400, 404, 500}},
+	},
+	"/banners.history": {
+		http.MethodGet: {Summary: "List versions of banner", Parameters: []parameter{bannerIDQuery, envelopeParam},
+			Result: []versionResponse{}, Errors: []int{400, 404, 500}},
+	},
+	"/banners.rollback": {
+		http.MethodPost: {Summary: "Roll banner back to version", Parameters: []parameter{ifMatchHeader, envelopeParam},
+			Form:   []parameter{bannerIDField, {Name: "version", Type: "integer", Required: true}},
+			Result: bannerResponse{}, Errors: []int{400, 404, 412, 428, 500}},
+	},
+	"/banners.restoreById": {
+		http.MethodPost: {Summary: "Restore soft deleted banner", Parameters: []parameter{envelopeParam},
+			Form: []parameter{bannerIDField}, Result: bannerResponse{}, Errors: []int{400, 404, 500}},
+	},
+	"/banners.purgeById": {
+		http.MethodPost: {Summary: "Delete soft deleted banner permanently", Parameters: []parameter{envelopeParam},
+			Form: []parameter{bannerIDField}, Result: bannerResponse{}, Errors: []int{400, 404, 500}},
+	},
+	"/banners.click": {
+		http.MethodGet: {Summary: "Count click and redirect to banner link", Parameters: []parameter{bannerIDQuery},
+			Status: http.StatusFound, Errors: []int{400, 404}},
+	},
+	"/banners.impression": {
+		http.MethodGet: {Summary: "Count impression, returns transparent pixel", Parameters: []parameter{bannerIDQuery},
+			ResultMedia: []string{"image/gif"}, Errors: []int{400, 404}},
+		http.MethodPost: {Summary: "Count impression", Parameters: []parameter{bannerIDQuery},
+			Status: http.StatusNoContent, Errors: []int{400, 404}},
+	},
+	"/banners.stats": {
+		http.MethodGet: {Summary: "Impressions, clicks and CTR over period", Parameters: []parameter{
+			{Name: "from", In: "query", Type: "string", Description: "RFC 3339 time, 24 hours before to by default"},
+			{Name: "to", In: "query", Type: "string", Description: "RFC 3339 time, now by default"},
+			{Name: "id", In: "query", Type: "integer", Description: "banner id, all banners when omitted"},
+			envelopeParam,
+		}, Result: statsResponse{}, Errors: []int{400}},
+	},
+	"/banners.export": {
+		http.MethodGet: {Summary: "Download all banners", Parameters: []parameter{
+			{Name: "format", In: "query", Type: "string", Description: "jsonl (default), csv or zip with banners.jsonl and images/"},
+		}, ResultMedia: []string{exportMediaTypes[formatJSONL], exportMediaTypes[formatCSV], exportMediaTypes[formatZIP]},
+			Errors: []int{400, 500}},
+	},
+	"/banners.import": {
+		http.MethodPost: {Summary: "Validate and save banners of export file", Parameters: []parameter{
+			{Name: "format", In: "query", Type: "string", Description: "jsonl, csv or zip, taken from Content-Type when omitted"},
+			{Name: "mode", In: "query", Type: "string", Description: "upsert (default) keeps ids, remap gives new ones"},
+			{Name: "dry_run", In: "query", Type: "boolean", Description: "only validate and report what would change"},
+			envelopeParam,
+		}, BodyTypes: []string{exportMediaTypes[formatJSONL], "text/csv", exportMediaTypes[formatZIP]},
+			Result: banners.ImportReport{}, FailStatus: http.StatusBadRequest, Errors: []int{413, 500}},
+	},
+	"/banners.batch": {
+		http.MethodPost: {Summary: "Create, update and delete banners at once, atomically or each on its own",
+			Parameters: []parameter{envelopeParam}, Body: batchRequest{}, BodyTypes: []string{"application/json"},
+			Result: batchResponse{}, FailStatus: http.StatusBadRequest, Errors: []int{500}},
+	},
+	"/banners.search": {
+		http.MethodGet: {Summary: "Find banners by words of title, content and button", Parameters: []parameter{
+			{Name: "q", In: "query", Type: "string", Description: "words, each matches word starting with it", Required: true},
+			{Name: "limit", In: "query", Type: "integer", Description: "number of best matches, 20 by default and 100 at most"},
+			envelopeParam,
+		}, Result: []searchHitResponse{}, Errors: []int{400, 500}},
+	},
+	"/experiments.getAll": {
+		http.MethodGet: {Summary: "List experiments", Parameters: []parameter{envelopeParam},
+			Result: []experimentResponse{}, Errors: []int{500}},
+	},
+	"/experiments.getById": {
+		http.MethodGet: {Summary: "Get experiment", Parameters: []parameter{experimentIDQuery, envelopeParam},
+			Result: experimentResponse{}, Errors: []int{400, 404}},
+	},
+	"/experiments.save": {
+		http.MethodPost: {Summary: "Create (id is 0) or update experiment", Parameters: []parameter{envelopeParam},
+			Form: []parameter{
+				{Name: "id", Type: "integer", Description: "experiment id", Required: true},
+				{Name: "name", Type: "string", Required: true},
+				{Name: "running", Type: "boolean"},
+				{Name: "variants", Type: "string", Description: "list of banner_id:weight, e.g. 1:50,2:50", Required: true},
+			}, Result: experimentResponse{}, Errors: []int{400, 404, 500}},
+	},
+	"/experiments.removeById": {
+		http.MethodPost: {Summary: "Delete experiment", Parameters: []parameter{experimentIDQuery, envelopeParam},
+			Result: experimentResponse{}, Errors: []int{400, 404, 500}},
+	},
+	"/experiments.assign": {
+		http.MethodGet: {Summary: "Get banner of variant assigned to visitor", Parameters: []parameter{experimentIDQuery,
+			{Name: "visitor", In: "query", Type: "string", Description: "visitor id, " + visitorCookie + " cookie is used when omitted"},
+			envelopeParam,
+		}, Result: bannerResponse{}, Errors: []int{204, 400, 404, 500}},
+	},
+	"/experiments.results": {
+		http.MethodGet: {Summary: "Experiment with significance of its variants", Parameters: []parameter{experimentIDQuery, envelopeParam},
+			Result: experimentResponse{}, Errors: []int{400, 404}},
+	},
+	bannersPath: {
+		http.MethodGet: {Summary: "List banners", Parameters: []parameter{envelopeParam},
+			Result: []bannerResponse{}, Errors: []int{500}},
+		http.MethodPost: {Role: restWriteRole, Summary: "Create banner", Parameters: []parameter{envelopeParam},
+			Body: bannerRequest{}, BodyTypes: []string{"application/json"}, Form: bannerForm,
+			Status: http.StatusCreated, Result: bannerResponse{}, Errors: []int{400, 415, 500}},
+	},
+	bannersPath + "/": {
+		http.MethodGet: {Summary: "Get banner", Parameters: []parameter{ifNoneMatch, envelopeParam},
+			Result: bannerResponse{}, Errors: []int{304, 404, 500}},
+		http.MethodPut: {Role: restWriteRole, Summary: "Replace banner", Parameters: []parameter{ifMatchHeader, envelopeParam},
+			Body: bannerRequest{}, BodyTypes: []string{"application/json"}, Form: bannerForm,
+			Result: bannerResponse{}, Errors: []int{400, 404, 412, 415, 428, 500}},
+		http.MethodPatch: {Role: restWriteRole, Summary: "Change banner by JSON merge-patch", Parameters: []parameter{ifMatchHeader, envelopeParam},
+			Body: bannerRequest{}, BodyTypes: []string{"application/merge-patch+json", "application/json"},
+			Result: bannerResponse{}, Errors: []int{400, 404, 412, 415, 428, 500}},
+		http.MethodDelete: {Role: restWriteRole, Summary: "Soft delete banner", Parameters: []parameter{ifMatchHeader},
+			Status: http.StatusNoContent, Errors: []int{404, 412, 500}},
+	},
+	"/healthz": {
+		http.MethodGet: {Summary: "Liveness probe", Result: map[string]string{}, Plain: true},
+	},
+	"/readyz": {
+		http.MethodGet: {Summary: "Readiness probe running registered checks", Result: health.Report{}, Plain: true,
+			FailStatus: http.StatusServiceUnavailable},
+	},
+	"/version": {
+		http.MethodGet: {Summary: "Module version and VCS information", Result: health.BuildInfo{}, Plain: true},
+	},
+	"/metrics": {
+		http.MethodGet: {Summary: "Metrics in Prometheus text format", ResultMedia: []string{"text/plain"}},
+	},
+	openAPIPath: {
+		http.MethodGet: {Summary: "This document", ResultMedia: []string{"application/json"}},
+	},
+}
+
+// schemaNames are types described once in components and referenced elsewhere
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeOf(bannerResponse{}):          "Banner",
+	reflect.TypeOf(bannerRequest{}):           "BannerInput",
+	reflect.TypeOf(targetingPayload{}):        "Targeting",
+	reflect.TypeOf(imageResponse{}):           "Image",
+	reflect.TypeOf(experimentResponse{}):      "Experiment",
+	reflect.TypeOf(variantResponse{}):         "ExperimentVariant",
+	reflect.TypeOf(banners.VariantResult{}):   "VariantResult",
+	reflect.TypeOf(versionResponse{}):         "Version",
+	reflect.TypeOf(changeResponse{}):          "FieldChange",
+	reflect.TypeOf(statsResponse{}):           "StatsReport",
+	reflect.TypeOf(banners.Stats{}):           "Stats",
+	reflect.TypeOf(banners.ValidationError{}): "ValidationError",
+	reflect.TypeOf(banners.FieldError{}):      "FieldError",
+	reflect.TypeOf(health.Report{}):           "Readiness",
+	reflect.TypeOf(health.Result{}):           "CheckResult",
+	reflect.TypeOf(health.BuildInfo{}):        "BuildInfo",
+	reflect.TypeOf(banners.ImportReport{}):    "ImportReport",
+	reflect.TypeOf(banners.ImportResult{}):    "ImportResult",
+	reflect.TypeOf(batchRequest{}):            "BatchInput",
+	reflect.TypeOf(batchOperation{}):          "BatchOperation",
+	reflect.TypeOf(batchResponse{}):           "BatchResult",
+	reflect.TypeOf(batchItemResponse{}):       "BatchItemResult",
+	reflect.TypeOf(searchHitResponse{}):       "SearchHit",
+}
+
+// schemaEnums are allowed values of string types
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(banners.Status("")):     {string(banners.StatusDraft), string(banners.StatusPublished), string(banners.StatusArchived)},
+	reflect.TypeOf(banners.Device("")):     {string(banners.DeviceDesktop), string(banners.DeviceMobile), string(banners.DeviceTablet)},
+	reflect.TypeOf(banners.ImportMode("")): {string(banners.ImportUpsert), string(banners.ImportRemap)},
+	reflect.TypeOf(banners.Action("")): {
+		string(banners.ActionCreate), string(banners.ActionUpdate), string(banners.ActionDelete),
+		string(banners.ActionRestore), string(banners.ActionRollback),
+	},
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// handle registers handler allowed to callers having role in mux
+// and remembers pattern, so it is described by OpenAPI document
+func (s *Server) handle(pattern string, role auth.Role, handler http.HandlerFunc) {
+	s.patterns = append(s.patterns, pattern)
+	s.roles[pattern] = role
+	s.mux.HandleFunc(pattern, s.authorized(role, handler))
+}
+
+func (s *Server) handleOpenAPI(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, http.StatusOK, s.openAPIDocument())
+}
+
+// openAPIDocument describes registered routes as OpenAPI 3 document
+func (s *Server) openAPIDocument() map[string]interface{} {
+	paths := make(map[string]interface{})
+	for _, pattern := range s.patterns {
+		path := pattern
+		var pathParams []interface{}
+		if strings.HasSuffix(pattern, "/") {
+			path += "{id}"
+			pathParams = append(pathParams, map[string]interface{}{
+				"name": "id", "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "integer", "format": "int64"},
+			})
+		}
+
+		item := make(map[string]interface{})
+		for method, op := range apiPaths[pattern] {
+			role := s.roles[pattern]
+			if op.Role > role {
+				role = op.Role
+			}
+			item[strings.ToLower(method)] = op.document(pathParams, role)
+		}
+		paths[path] = item
+	}
+
+	schemas := make(map[string]interface{}, len(schemaNames))
+	for typ, name := range schemaNames {
+		schemas[name] = objectSchema(typ)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Banners API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{"type": "apiKey", "in": "header", "name": auth.APIKeyHeader},
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"basic":  map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+func (op operation) document(pathParams []interface{}, role auth.Role) map[string]interface{} {
+	params := append([]interface{}(nil), pathParams...)
+	for _, param := range op.Parameters {
+		params = append(params, map[string]interface{}{
+			"name":        param.Name,
+			"in":          param.In,
+			"description": param.Description,
+			"required":    param.Required,
+			"schema":      param.schema(),
+		})
+	}
+
+	responses := op.responses()
+	document := map[string]interface{}{
+		"summary":   op.Summary,
+		"responses": responses,
+	}
+	if role != auth.RoleNone {
+		document["description"] = "Requires role " + role.String() + " or higher."
+		document["security"] = []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"basic": []string{}},
+		}
+		responses["401"] = map[string]interface{}{"description": http.StatusText(http.StatusUnauthorized)}
+		responses["403"] = map[string]interface{}{"description": http.StatusText(http.StatusForbidden)}
+	}
+	if len(params) != 0 {
+		document["parameters"] = params
+	}
+
+	content := make(map[string]interface{})
+	for _, mediaType := range op.BodyTypes {
+		if op.Body != nil {
+			content[mediaType] = map[string]interface{}{"schema": typeSchema(reflect.TypeOf(op.Body))}
+		} else {
+			content[mediaType] = map[string]interface{}{"schema": parameter{Type: "binary"}.schema()}
+		}
+	}
+	if len(op.Form) != 0 {
+		properties := make(map[string]interface{}, len(op.Form))
+		required := make([]string, 0)
+		multipart := false
+		for _, field := range op.Form {
+			schema := field.schema()
+			if field.Description != "" {
+				schema["description"] = field.Description
+			}
+			properties[field.Name] = schema
+			if field.Required {
+				required = append(required, field.Name)
+			}
+			multipart = multipart || field.Type == "binary"
+		}
+		form := map[string]interface{}{"type": "object", "properties": properties}
+		if len(required) != 0 {
+			form["required"] = required
+		}
+		content["multipart/form-data"] = map[string]interface{}{"schema": form}
+		if !multipart {
+			content["application/x-www-form-urlencoded"] = map[string]interface{}{"schema": form}
+		}
+	}
+	if len(content) != 0 {
+		document["requestBody"] = map[string]interface{}{"required": true, "content": content}
+	}
+	return document
+}
+
+func (op operation) responses() map[string]interface{} {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case op.Result != nil && op.Plain:
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": typeSchema(reflect.TypeOf(op.Result))},
+		}
+	case op.Result != nil:
+		schema := typeSchema(reflect.TypeOf(op.Result))
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+			envelopeMediaType: map[string]interface{}{"schema": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"data": schema,
+					"meta": map[string]interface{}{"type": "object"},
+				},
+			}},
+		}
+	case len(op.ResultMedia) != 0:
+		content := make(map[string]interface{}, len(op.ResultMedia))
+		for _, mediaType := range op.ResultMedia {
+			content[mediaType] = map[string]interface{}{"schema": parameter{Type: "binary"}.schema()}
+		}
+		success["content"] = content
+	}
+
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	if op.FailStatus != 0 {
+		responses[strconv.Itoa(op.FailStatus)] = map[string]interface{}{
+			"description": http.StatusText(op.FailStatus),
+			"content":     success["content"],
+		}
+	}
+	for _, code := range op.Errors {
+		response := map[string]interface{}{"description": http.StatusText(code)}
+		switch {
+		case code == http.StatusBadRequest:
+			response["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": typeSchema(reflect.TypeOf(banners.ValidationError{}))},
+				"text/plain":       map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}
+		case code >= 400:
+			response["content"] = map[string]interface{}{
+				"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			}
+		}
+		responses[strconv.Itoa(code)] = response
+	}
+	return responses
+}
+
+func (p parameter) schema() map[string]interface{} {
+	switch p.Type {
+	case "binary":
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	case "integer":
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	}
+	return map[string]interface{}{"type": p.Type}
+}
+
+// typeSchema describes JSON encoding of Go type, named types become references
+func typeSchema(typ reflect.Type) map[string]interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if name, ok := schemaNames[typ]; ok {
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	if typ == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		schema := map[string]interface{}{"type": "string"}
+		if values, ok := schemaEnums[typ]; ok {
+			schema["enum"] = values
+		}
+		return schema
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(typ.Elem())}
+	case reflect.Struct:
+		return objectSchema(typ)
+	}
+	return map[string]interface{}{}
+}
+
+// objectSchema describes struct by its json tags, embedded structs are flattened
+func objectSchema(typ reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	var walk func(typ reflect.Type)
+	walk = func(typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				walk(field.Type)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+			tag := strings.Split(field.Tag.Get("json"), ",")
+			name := tag[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = typeSchema(field.Type)
+			if len(tag) == 1 || tag[1] != "omitempty" {
+				required = append(required, name)
+			}
+		}
+	}
+	walk(typ)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) != 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
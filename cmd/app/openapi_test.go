@@ -0,0 +1,72 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIDocumentsRoutes(t *testing.T) {
+	server, _ := testServer(t)
+	response := testRequest(server, http.MethodGet, openAPIPath, nil, nil)
+	if response.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", response.Code, response.Body)
+	}
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	registered := make(map[string]bool)
+	for _, pattern := range server.patterns {
+		registered[pattern] = true
+		path, target := pattern, pattern
+		if strings.HasSuffix(pattern, "/") {
+			path, target = pattern+"{id}", pattern+"1"
+		}
+
+		t.Run(pattern, func(t *testing.T) {
+			operations, ok := document.Paths[path]
+			if !ok || len(operations) == 0 {
+				t.Fatalf("path %s is not documented", path)
+			}
+			for method := range apiPaths[pattern] {
+				if _, ok := operations[strings.ToLower(method)]; !ok {
+					t.Errorf("%s %s is not documented", method, path)
+				}
+			}
+
+			// route checking method must document every method it accepts and nothing else
+			allowed := make(map[string]bool)
+			checked := false
+			for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+				allowed[method] = testRequest(server, method, target, nil, nil).Code != http.StatusMethodNotAllowed
+				checked = checked || !allowed[method]
+			}
+			for method, ok := range allowed {
+				_, documented := operations[strings.ToLower(method)]
+				if documented && !ok {
+					t.Errorf("%s %s is documented but answers 405", method, path)
+				}
+				if !documented && ok && checked {
+					t.Errorf("%s %s is accepted but not documented", method, path)
+				}
+			}
+		})
+	}
+
+	var stale []string
+	for pattern := range apiPaths {
+		if !registered[pattern] {
+			stale = append(stale, pattern)
+		}
+	}
+	sort.Strings(stale)
+	if len(stale) != 0 {
+		t.Errorf("documented routes are not registered: %v", stale)
+	}
+}
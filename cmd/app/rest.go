@@ -0,0 +1,254 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// bannersPath is collection of RESTful banners API, single banner lives at bannersPath/{id}
+const bannersPath = "/api/v1/banners"
+
+// maxJSONBody limits size of JSON request bodies
+const maxJSONBody = 1 << 20
+
+// restWriteRole is required by methods changing banners, reading needs role of route
+const restWriteRole = auth.RoleEditor
+
+// handleBannersCollection serves GET and POST of bannersPath
+func (s *Server) handleBannersCollection(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+		s.handleGetAllBanners(writer, request)
+	case http.MethodPost:
+		request, ok := s.authorize(writer, request, restWriteRole)
+		if !ok {
+			return
+		}
+		s.restSaveBanner(writer, request, 0)
+	default:
+		methodNotAllowed(writer, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleBannerResource serves GET, PUT, PATCH and DELETE of bannersPath/{id}
+func (s *Server) handleBannerResource(writer http.ResponseWriter, request *http.Request) {
+	idParam := strings.TrimPrefix(request.URL.Path, bannersPath+"/")
+	if idParam == "" {
+		s.handleBannersCollection(writer, request)
+		return
+	}
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil || id <= 0 {
+		http.NotFound(writer, request)
+		return
+	}
+
+	switch request.Method {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		var ok bool
+		request, ok = s.authorize(writer, request, restWriteRole)
+		if !ok {
+			return
+		}
+	}
+
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+		s.restGetBanner(writer, request, id)
+	case http.MethodPut:
+		s.restSaveBanner(writer, request, id)
+	case http.MethodPatch:
+		s.restPatchBanner(writer, request, id)
+	case http.MethodDelete:
+		s.restDeleteBanner(writer, request, id)
+	default:
+		methodNotAllowed(writer, http.MethodGet, http.MethodPut, http.MethodPatch, http.MethodDelete)
+	}
+}
+
+func (s *Server) restGetBanner(writer http.ResponseWriter, request *http.Request, id int64) {
+	item, err := s.bannersSvc.ByID(request.Context(), id)
+	if err == nil && matchETag(request.Header.Get("If-None-Match"), etag(item)) {
+		writer.Header().Set("ETag", etag(item))
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.writeBanner(writer, request, http.StatusOK, item, err)
+}
+
+// restSaveBanner creates (id is 0) or replaces banner from JSON or form body
+func (s *Server) restSaveBanner(writer http.ResponseWriter, request *http.Request, id int64) {
+	contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if contentType == "application/json" {
+		var input bannerRequest
+		if !decodeJSON(writer, request, &input) {
+			return
+		}
+		s.saveBannerREST(writer, request, input.banner(id), nil)
+		return
+	}
+
+	if contentType != "multipart/form-data" && contentType != "application/x-www-form-urlencoded" {
+		http.Error(writer, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	banner, formErr := bannerFromForm(request, id)
+	if formErr != nil {
+		logger(request).Debug("invalid request", "error", formErr)
+		writeJSON(writer, http.StatusBadRequest, formErr)
+		return
+	}
+	image, err := formImage(request)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if image != nil {
+		defer image.Close()
+	}
+	s.saveBannerREST(writer, request, banner, image)
+}
+
+// restPatchBanner applies JSON merge-patch (RFC 7396) to writable fields of banner
+func (s *Server) restPatchBanner(writer http.ResponseWriter, request *http.Request, id int64) {
+	contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if contentType != "application/merge-patch+json" && contentType != "application/json" {
+		writer.Header().Set("Accept-Patch", "application/merge-patch+json")
+		http.Error(writer, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var patch interface{}
+	if !decodeJSON(writer, request, &patch) {
+		return
+	}
+
+	current, err := s.bannersSvc.ByID(request.Context(), id)
+	if err != nil {
+		s.writeBanner(writer, request, http.StatusOK, nil, err)
+		return
+	}
+
+	data, err := json.Marshal(requestFromBanner(current))
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var document interface{}
+	if err = json.Unmarshal(data, &document); err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	patched, err := json.Marshal(mergePatch(document, patch))
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var input bannerRequest
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&input); err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	s.saveBannerREST(writer, request, input.banner(id), nil)
+}
+
+func (s *Server) restDeleteBanner(writer http.ResponseWriter, request *http.Request, id int64) {
+	if header := request.Header.Get("If-Match"); header != "" {
+		current, err := s.bannersSvc.ByID(request.Context(), id)
+		if err != nil {
+			s.writeBanner(writer, request, http.StatusOK, nil, err)
+			return
+		}
+		if !matchStrongETag(header, etag(current)) {
+			http.Error(writer, http.StatusText(http.StatusPreconditionFailed), http.StatusPreconditionFailed)
+			return
+		}
+	}
+
+	_, err := s.bannersSvc.RemoveByID(request.Context(), id)
+	if err != nil {
+		s.writeBanner(writer, request, http.StatusOK, nil, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// saveBannerREST saves banner, created one is answered with 201 and Location
+func (s *Server) saveBannerREST(writer http.ResponseWriter, request *http.Request, banner *banners.Banner, image multipart.File) {
+	if banner.ID == 0 {
+		item, err := s.bannersSvc.Save(request.Context(), banner, image)
+		if err == nil {
+			writer.Header().Set("Location", bannersPath+"/"+strconv.FormatInt(item.ID, 10))
+		}
+		s.writeBanner(writer, request, http.StatusCreated, item, err)
+		return
+	}
+	s.saveBanner(writer, request, banner, image, http.StatusOK)
+}
+
+// decodeJSON reads body into value rejecting unknown fields, it writes 400 on failure
+func decodeJSON(writer http.ResponseWriter, request *http.Request, value interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxJSONBody))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(value)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		writeJSON(writer, http.StatusBadRequest, &banners.ValidationError{Errors: []banners.FieldError{
+			{Field: "body", Message: jsonErrorMessage(err)},
+		}})
+		return false
+	}
+	return true
+}
+
+func jsonErrorMessage(err error) string {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return "field " + strconv.Quote(typeErr.Field) + " must be " + typeErr.Type.String()
+	}
+	return err.Error()
+}
+
+// mergePatch applies RFC 7396 patch to target
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+func methodNotAllowed(writer http.ResponseWriter, methods ...string) {
+	writer.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"replace value", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add value", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"null deletes", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"null of missing", `{"a":"b"}`, `{"c":null}`, `{"a":"b"}`},
+		{"nested object is merged", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"f","d":null}}`, `{"a":{"b":"f"}}`},
+		{"nested object replaces value", `{"a":"b"}`, `{"a":{"c":"d","e":null}}`, `{"a":{"c":"d"}}`},
+		{"array is replaced", `{"a":["b","c"]}`, `{"a":["d"]}`, `{"a":["d"]}`},
+		{"not object replaces target", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"empty patch", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var target, patch, want interface{}
+			for _, value := range []struct {
+				data string
+				into *interface{}
+			}{{test.target, &target}, {test.patch, &patch}, {test.want, &want}} {
+				if err := json.Unmarshal([]byte(value.data), value.into); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := mergePatch(target, patch); !reflect.DeepEqual(got, want) {
+				t.Errorf("mergePatch(%s, %s) = %v, want %s", test.target, test.patch, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRESTMethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		target string
+		allow  string
+	}{
+		{"collection", http.MethodDelete, bannersPath, "GET, POST"},
+		{"resource", http.MethodPost, bannersPath + "/1", "GET, PUT, PATCH, DELETE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, _ := testServer(t)
+			response := testRequest(server, test.method, test.target, nil, nil)
+			if response.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("status = %d, want %d", response.Code, http.StatusMethodNotAllowed)
+			}
+			if allow := response.Header().Get("Allow"); allow != test.allow {
+				t.Errorf("Allow = %q, want %q", allow, test.allow)
+			}
+		})
+	}
+}
+
+func TestRESTIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		ifMatch string
+		status  int
+	}{
+		{"put without If-Match", http.MethodPut, "", http.StatusPreconditionRequired},
+		{"put of current", http.MethodPut, `"1"`, http.StatusOK},
+		{"put of stale", http.MethodPut, `"0"`, http.StatusPreconditionFailed},
+		{"patch without If-Match", http.MethodPatch, "", http.StatusPreconditionRequired},
+		{"patch of current", http.MethodPatch, `"1"`, http.StatusOK},
+		{"patch of stale", http.MethodPatch, `"0"`, http.StatusPreconditionFailed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, svc := testServer(t)
+			ctx := context.Background()
+			item, err := svc.Save(ctx, &banners.Banner{Title: "Sale", Content: "Half price", Button: "Buy"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := http.Header{"Content-Type": {"application/json"}}
+			body := `{"title":"Big sale","content":"Half price"}`
+			if test.method == http.MethodPatch {
+				header.Set("Content-Type", "application/merge-patch+json")
+				body = `{"title":"Big sale","button":null}`
+			}
+			if test.ifMatch != "" {
+				header.Set("If-Match", test.ifMatch)
+			}
+
+			target := bannersPath + "/" + strconv.FormatInt(item.ID, 10)
+			response := testRequest(server, test.method, target, strings.NewReader(body), header)
+			if response.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+
+			saved, err := svc.ByID(ctx, item.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := &banners.Banner{Title: "Sale", Content: "Half price", Button: "Buy"}
+			if test.status == http.StatusOK {
+				want = &banners.Banner{Title: "Big sale", Content: "Half price"}
+				if tag := response.Header().Get("ETag"); tag != `"2"` {
+					t.Errorf("ETag = %q, want revision 2", tag)
+				}
+			}
+			if saved.Title != want.Title || saved.Content != want.Content || saved.Button != want.Button {
+				t.Errorf("saved %+v, want %+v", saved, want)
+			}
+		})
+	}
+}
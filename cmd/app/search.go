@@ -0,0 +1,59 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// Number of banners answered by /banners.search by default and at most
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+type searchHitResponse struct {
+	Banner bannerResponse `json:"banner"`
+	Score  float64        `json:"score"`
+	// Highlights are snippets of matched fields with matched words in <mark>, HTML escaped
+	Highlights map[string]string `json:"highlights"`
+}
+
+func (s *Server) handleSearchBanners(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	verr := &banners.ValidationError{}
+	if q == "" {
+		verr.Errors = append(verr.Errors, banners.FieldError{Field: "q", Message: "is required"})
+	}
+	limit := defaultSearchLimit
+	if raw := query.Get("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			verr.Errors = append(verr.Errors, banners.FieldError{
+				Field: "limit", Message: "must be integer from 1 to " + strconv.Itoa(maxSearchLimit),
+			})
+		}
+	}
+	if len(verr.Errors) != 0 {
+		logger(request).Debug("invalid request", "error", verr)
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	}
+
+	hits, err := s.bannersSvc.Search(request.Context(), q, limit)
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]searchHitResponse, len(hits))
+	for i, hit := range hits {
+		response[i] = searchHitResponse{Banner: s.bannerResponse(hit.Banner), Score: hit.Score, Highlights: hit.Highlights}
+	}
+	respond(writer, request, http.StatusOK, response, map[string]interface{}{"count": len(response), "query": q})
+}
@@ -2,154 +2,387 @@ package app
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path"
 	"strconv"
-	"strings"
+	"time"
 
+	"github.com/MrHakimov/http/pkg/auth"
 	"github.com/MrHakimov/http/pkg/banners"
+	"github.com/MrHakimov/http/pkg/health"
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/metrics"
+	"github.com/MrHakimov/http/pkg/storage"
+	"github.com/MrHakimov/http/pkg/tracing"
 )
 
 // Server class for main data
 type Server struct {
-	mux        *http.ServeMux
-	bannersSvc *banners.Service
+	mux         *http.ServeMux
+	bannersSvc  *banners.Service
+	tracker     *banners.Tracker
+	experiments *banners.Experiments
+	health      *health.Registry
+	metrics     *metrics.Registry
+	httpMetrics *httpMetrics
+	// patterns are routes registered by Init
+	patterns      []string
+	roles         map[string]auth.Role
+	authenticator auth.Authenticator
+	// handler is mux wrapped by middlewares
+	handler http.Handler
+	logger  *logging.Logger
+	tracer  *tracing.Tracer
 }
 
-// NewServer creates new server
+// NewServer creates new server, stats are flushed to store of bannersSvc
+// and operations of bannersSvc are counted in metrics of server
 func NewServer(mux *http.ServeMux, bannersSvc *banners.Service) *Server {
-	return &Server{mux: mux, bannersSvc: bannersSvc}
+	registry := metrics.NewRegistry()
+	bannersSvc.SetMetrics(registry)
+	return &Server{
+		mux:         mux,
+		bannersSvc:  bannersSvc,
+		tracker:     banners.NewTracker(bannersSvc.Store()),
+		experiments: banners.NewExperiments(bannersSvc),
+		health:      newHealth(bannersSvc),
+		metrics:     registry,
+		httpMetrics: newHTTPMetrics(registry),
+		roles:       make(map[string]auth.Role),
+		handler:     mux,
+	}
+}
+
+// Tracker returns impressions and clicks tracker, caller should Run it to flush stats
+func (s *Server) Tracker() *banners.Tracker {
+	return s.tracker
+}
+
+// Health returns readiness checks, repository check is registered by NewServer
+func (s *Server) Health() *health.Registry {
+	return s.health
 }
 
-// ServeHTTP calls s.mux.ServeHTTP
+// newHealth checks that store of bannersSvc answers reads
+func newHealth(bannersSvc *banners.Service) *health.Registry {
+	registry := health.NewRegistry()
+	registry.Register("repository", health.Store(bannersSvc.Store()))
+	return registry
+}
+
+// Use adds middleware wrapping all routes, middleware added last runs first
+func (s *Server) Use(middleware func(http.Handler) http.Handler) {
+	s.handler = middleware(s.handler)
+}
+
+// ServeHTTP calls s.mux.ServeHTTP through middlewares, records request metrics, traces request and writes access log
 func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
-	s.mux.ServeHTTP(writer, request)
+	s.trace(writer, s.withRequestLogger(writer, request), func(writer http.ResponseWriter, request *http.Request) {
+		s.observe(writer, request, s.handler)
+	})
 }
 
-// Init initializes all supported operations
+// Init initializes all supported operations together with roles they require
 func (s *Server) Init() {
-	log.Println("Init method")
-	s.mux.HandleFunc("/banners.getAll", s.handleGetAllBanners)
-	s.mux.HandleFunc("/banners.getById", s.handleGetBannerById)
-	s.mux.HandleFunc("/banners.save", s.handleSaveBanner)
-	s.mux.HandleFunc("/banners.removeById", s.handleRemoveById)
+	s.handle("/banners.getAll", auth.RoleViewer, s.handleGetAllBanners)
+	s.handle("/banners.getById", auth.RoleViewer, s.handleGetBannerById)
+	s.handle("/banners.getActive", auth.RoleNone, s.handleGetActiveBanners)
+	s.handle("/banners.serve", auth.RoleNone, s.handleServeBanner)
+	s.handle("/banners.save", auth.RoleEditor, s.handleSaveBanner)
+	s.handle("/banners.removeById", auth.RoleEditor, s.handleRemoveById)
+	s.handle("/banners.image", auth.RoleNone, s.handleGetImage)
+	s.handle("/banners.history", auth.RoleViewer, s.handleHistory)
+	s.handle("/banners.rollback", auth.RoleEditor, s.handleRollback)
+	s.handle("/banners.restoreById", auth.RoleEditor, s.handleRestoreById)
+	s.handle("/banners.purgeById", auth.RoleAdmin, s.handlePurgeById)
+	s.handle("/banners.click", auth.RoleNone, s.handleClick)
+	s.handle("/banners.impression", auth.RoleNone, s.handleImpression)
+	s.handle("/banners.stats", auth.RoleViewer, s.handleStats)
+	s.handle("/banners.export", auth.RoleViewer, s.handleExportBanners)
+	s.handle("/banners.import", auth.RoleEditor, s.handleImportBanners)
+	s.handle("/banners.batch", auth.RoleEditor, s.handleBatchBanners)
+	s.handle("/banners.search", auth.RoleViewer, s.handleSearchBanners)
+	s.handle("/experiments.getAll", auth.RoleViewer, s.handleGetAllExperiments)
+	s.handle("/experiments.getById", auth.RoleViewer, s.handleGetExperimentById)
+	s.handle("/experiments.save", auth.RoleEditor, s.handleSaveExperiment)
+	s.handle("/experiments.removeById", auth.RoleEditor, s.handleRemoveExperimentById)
+	s.handle("/experiments.assign", auth.RoleNone, s.handleAssignExperiment)
+	s.handle("/experiments.results", auth.RoleViewer, s.handleExperimentResults)
+
+	s.handle(bannersPath, auth.RoleViewer, s.handleBannersCollection)
+	s.handle(bannersPath+"/", auth.RoleViewer, s.handleBannerResource)
+	s.handle("/healthz", auth.RoleNone, health.HandleLiveness)
+	s.handle("/readyz", auth.RoleNone, s.health.HandleReadiness)
+	s.handle("/version", auth.RoleNone, health.HandleVersion)
+	s.handle("/metrics", auth.RoleViewer, s.metrics.Handler)
+	s.handle(openAPIPath, auth.RoleNone, s.handleOpenAPI)
 }
 
 func (s *Server) handleGetAllBanners(writer http.ResponseWriter, request *http.Request) {
 	items, err := s.bannersSvc.All(request.Context())
 	if err != nil {
-		log.Println(err)
+		logger(request).Error("request failed", "error", err)
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	data, err := json.Marshal(items)
+	respond(writer, request, http.StatusOK, s.bannersResponse(items), map[string]interface{}{"count": len(items)})
+}
+
+func (s *Server) handleGetActiveBanners(writer http.ResponseWriter, request *http.Request) {
+	items, err := s.bannersSvc.Active(request.Context(), time.Now())
 	if err != nil {
-		log.Println(err)
+		logger(request).Error("request failed", "error", err)
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	jsonResponse(writer, data)
+	respond(writer, request, http.StatusOK, s.bannersResponse(items), map[string]interface{}{"count": len(items)})
 }
 
 func (s *Server) handleGetBannerById(writer http.ResponseWriter, request *http.Request) {
 	idParam := request.URL.Query().Get("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		log.Println(err)
+		logger(request).Debug("invalid request", "error", err)
 		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
 
 	item, err := s.bannersSvc.ByID(request.Context(), id)
+	if errors.Is(err, banners.ErrNotFound) {
+		logger(request).Debug("banner not found", "id", id)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Println(err)
+		logger(request).Error("request failed", "error", err)
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	data, err := json.Marshal(item)
-	if err != nil {
-		log.Println(err)
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	tag := etag(item)
+	writer.Header().Set("ETag", tag)
+	if matchETag(request.Header.Get("If-None-Match"), tag) {
+		writer.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	jsonResponse(writer, data)
+	respond(writer, request, http.StatusOK, s.bannerResponse(item), nil)
 }
 
 func (s *Server) handleSaveBanner(writer http.ResponseWriter, request *http.Request) {
-	log.Println("requestURI: ", request.RequestURI)
-
 	idParam := request.PostFormValue("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		log.Println(err)
+		logger(request).Debug("invalid request", "error", err)
 		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
+	banner, formErr := bannerFromForm(request, id)
+	if formErr != nil {
+		logger(request).Debug("invalid request", "error", formErr)
+		writeJSON(writer, http.StatusBadRequest, formErr)
+		return
+	}
+	image, err := formImage(request)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	if image != nil {
+		defer image.Close()
+	}
+
+	s.saveBanner(writer, request, banner, image, http.StatusOK)
+}
+
+// saveBanner checks If-Match of existing banner, saves it and writes result with status
+func (s *Server) saveBanner(writer http.ResponseWriter, request *http.Request, banner *banners.Banner, image multipart.File, status int) {
+	if banner.ID != 0 {
+		precondition := s.checkIfMatch(request, banner)
+		if precondition != http.StatusOK {
+			http.Error(writer, http.StatusText(precondition), precondition)
+			return
+		}
+	}
+
+	item, err := s.bannersSvc.Save(request.Context(), banner, image)
+	s.writeBanner(writer, request, status, item, err)
+}
+
+// formValues is source of form fields, *http.Request or row of imported CSV
+type formValues interface {
+	FormValue(key string) string
+}
+
+// bannerFromForm reads banner fields from url-encoded or multipart form
+func bannerFromForm(request formValues, id int64) (*banners.Banner, *banners.ValidationError) {
 	banner := &banners.Banner{
 		ID:      id,
 		Title:   request.FormValue("title"),
 		Content: request.FormValue("content"),
 		Button:  request.FormValue("button"),
 		Link:    request.FormValue("link"),
+		Status:  banners.Status(request.FormValue("status")),
 	}
-	image, header, err := request.FormFile("image")
-	if err == nil {
-		var name = strings.Split(header.Filename, ".")
-		//banner.Image = name[len(name)-1]
-		banner.Image = name[1]
+	formErr := &banners.ValidationError{}
+	parseSchedule(request, banner, formErr)
+	parseTargeting(request, banner, formErr)
+	if len(formErr.Errors) != 0 {
+		return nil, formErr
 	}
+	return banner, nil
+}
 
-	item, err := s.bannersSvc.Save(request.Context(), banner, image)
+// formImage returns uploaded image or nil when there is none, caller must close it
+func formImage(request *http.Request) (multipart.File, error) {
+	image, _, err := request.FormFile("image")
+	if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+		return nil, nil
+	}
 	if err != nil {
-		log.Println(err)
-		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, err
+	}
+	return image, nil
+}
+
+func (s *Server) handleRemoveById(writer http.ResponseWriter, request *http.Request) {
+	idParam := request.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
 
-	data, err := json.Marshal(item)
+	item, err := s.bannersSvc.RemoveByID(request.Context(), id)
+	if errors.Is(err, banners.ErrNotFound) {
+		logger(request).Debug("banner not found", "id", id)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Println(err)
+		logger(request).Error("request failed", "error", err)
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	jsonResponse(writer, data)
+	respond(writer, request, http.StatusOK, s.bannerResponse(item), nil)
 }
 
-func (s *Server) handleRemoveById(writer http.ResponseWriter, request *http.Request) {
+// maxDPR is highest device pixel ratio accepted by /banners.image
+const maxDPR = 4
+
+func (s *Server) handleGetImage(writer http.ResponseWriter, request *http.Request) {
 	idParam := request.URL.Query().Get("id")
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
-		log.Println(err)
+		logger(request).Debug("invalid request", "error", err)
 		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		return
 	}
 
-	item, err := s.bannersSvc.RemoveByID(request.Context(), id)
+	width := 0
+	if widthParam := request.URL.Query().Get("w"); widthParam != "" {
+		width, err = strconv.Atoi(widthParam)
+		if err != nil || width < 0 {
+			logger(request).Debug("invalid request", "width", widthParam)
+			http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+	// device pixel ratio scales width of display to pixels of image
+	if dprParam := request.URL.Query().Get("dpr"); dprParam != "" {
+		dpr, err := strconv.ParseFloat(dprParam, 64)
+		if err != nil || dpr < 1 || dpr > maxDPR {
+			logger(request).Debug("invalid request", "dpr", dprParam)
+			http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		width = int(math.Ceil(float64(width) * dpr))
+	}
+
+	item, err := s.bannersSvc.ByID(request.Context(), id)
+	if errors.Is(err, banners.ErrNotFound) {
+		logger(request).Debug("banner not found", "id", id)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Println(err)
+		logger(request).Error("request failed", "error", err)
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	if item.Image == "" {
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 
-	data, err := json.Marshal(item)
+	key := item.BestVariant(width)
+	reader, err := s.bannersSvc.Store().Get(request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
 	if err != nil {
-		log.Println(err)
+		logger(request).Error("request failed", "error", err)
 		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
+	defer reader.Close()
+
+	writer.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	_, err = io.Copy(writer, reader)
+	if err != nil {
+		logger(request).Warn("write response", "error", err)
+	}
+}
+
+// parseSchedule reads priority and activation window (RFC 3339 times) of banner
+func parseSchedule(request formValues, banner *banners.Banner, verr *banners.ValidationError) {
+	if priority := request.FormValue("priority"); priority != "" {
+		value, err := strconv.Atoi(priority)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "priority", Message: "must be integer"})
+		}
+		banner.Priority = value
+	}
 
-	jsonResponse(writer, data)
+	for _, field := range []struct {
+		name  string
+		value *time.Time
+	}{{"starts_at", &banner.StartsAt}, {"ends_at", &banner.EndsAt}} {
+		raw := request.FormValue(field.name)
+		if raw == "" {
+			continue
+		}
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: field.name, Message: "must be RFC 3339 time"})
+		}
+		*field.value = value
+	}
 }
 
-func jsonResponse(writer http.ResponseWriter, data []byte) {
+func writeJSON(writer http.ResponseWriter, status int, body interface{}) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		logging.Default().Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
 	writer.Header().Set("Content-Type", "application/json")
-	_, err := writer.Write(data)
+	writer.WriteHeader(status)
+	_, err = writer.Write(data)
 	if err != nil {
-		log.Println("Error write response: ", err)
+		logging.Default().Warn("write response", "error", err)
 	}
 }
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/banners"
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+func TestMain(m *testing.M) {
+	logging.SetDefault(logging.New(ioutil.Discard, logging.LevelInfo, logging.FormatLogfmt))
+	os.Exit(m.Run())
+}
+
+// testServer returns initialized server whose requests are authorized as admin by testRequest
+func testServer(t *testing.T) (*Server, *banners.Service) {
+	t.Helper()
+	svc := banners.NewService(storage.NewMemoryStore("http://cdn/"), 1<<20)
+	server := NewServer(http.NewServeMux(), svc)
+	server.SetAuthenticator(auth.NewAPIKeys(map[string]auth.Principal{
+		"admin-key": {Name: "admin", Role: auth.RoleAdmin},
+	}))
+	server.Init()
+	return server, svc
+}
+
+// testBanner saves valid banner
+func testBanner(t *testing.T, svc *banners.Service) *banners.Banner {
+	t.Helper()
+	item, err := svc.Save(context.Background(), &banners.Banner{Title: "Sale", Content: "Half price"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+// testRequest serves request of admin
+func testRequest(server *Server, method string, target string, body io.Reader, header http.Header) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, body)
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	request.Header.Set("X-API-Key", "admin-key")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestMissingBannerIsNotFound(t *testing.T) {
+	server, svc := testServer(t)
+	existing := testBanner(t, svc)
+	removed := testBanner(t, svc)
+	if _, err := svc.RemoveByID(context.Background(), removed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		status int
+	}{
+		{"get", http.MethodGet, "/banners.getById?id=" + strconv.FormatInt(existing.ID, 10), http.StatusOK},
+		{"get missing", http.MethodGet, "/banners.getById?id=999", http.StatusNotFound},
+		{"get removed", http.MethodGet, "/banners.getById?id=" + strconv.FormatInt(removed.ID, 10), http.StatusNotFound},
+		{"get invalid id", http.MethodGet, "/banners.getById?id=x", http.StatusBadRequest},
+		{"image of missing", http.MethodGet, "/banners.image?id=999", http.StatusNotFound},
+		{"remove missing", http.MethodPost, "/banners.removeById?id=999", http.StatusNotFound},
+		{"remove removed", http.MethodPost, "/banners.removeById?id=" + strconv.FormatInt(removed.ID, 10), http.StatusNotFound},
+		{"remove", http.MethodPost, "/banners.removeById?id=" + strconv.FormatInt(existing.ID, 10), http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := testRequest(server, test.method, test.target, nil, nil)
+			if response.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+		})
+	}
+}
+
+func TestImageVariant(t *testing.T) {
+	server, svc := testServer(t)
+	ctx := context.Background()
+	// 700 pixels wide image has 320 and 640 pixels wide variants
+	item, err := svc.Save(ctx, &banners.Banner{Title: "Sale", Content: "Half price"}, pngFile(t, 700, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "/banners.image?id=" + strconv.FormatInt(item.ID, 10)
+
+	tests := []struct {
+		name  string
+		query string
+		// key is served image, empty when request fails with status
+		key    string
+		status int
+	}{
+		{"original", "", item.Image, http.StatusOK},
+		{"small display", "&w=300", item.Variants[320], http.StatusOK},
+		{"exact width", "&w=640", item.Variants[640], http.StatusOK},
+		{"wider than variants", "&w=650", item.Image, http.StatusOK},
+		{"pixel ratio", "&w=300&dpr=2", item.Variants[640], http.StatusOK},
+		{"fractional pixel ratio", "&w=200&dpr=1.5", item.Variants[320], http.StatusOK},
+		{"pixel ratio beyond variants", "&w=300&dpr=3", item.Image, http.StatusOK},
+		{"pixel ratio below 1", "&w=300&dpr=0.5", "", http.StatusBadRequest},
+		{"pixel ratio too high", "&w=300&dpr=5", "", http.StatusBadRequest},
+		{"invalid pixel ratio", "&w=300&dpr=x", "", http.StatusBadRequest},
+		{"negative width", "&w=-1", "", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := testRequest(server, http.MethodGet, target+test.query, nil, nil)
+			if response.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+			if test.key == "" {
+				return
+			}
+			reader, err := svc.Store().Get(ctx, test.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			want, _ := ioutil.ReadAll(reader)
+			if !bytes.Equal(response.Body.Bytes(), want) {
+				t.Errorf("served image is not %s", test.key)
+			}
+		})
+	}
+}
+
+// wrappingStore is memory store wrapping errors of Get
+type wrappingStore struct {
+	*storage.MemoryStore
+}
+
+func (s wrappingStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	reader, err := s.MemoryStore.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", key, err)
+	}
+	return reader, nil
+}
+
+func TestMissingImageBlob(t *testing.T) {
+	store := wrappingStore{storage.NewMemoryStore("http://cdn/")}
+	svc := banners.NewService(store, 1<<20)
+	server := NewServer(http.NewServeMux(), svc)
+	server.Init()
+	ctx := context.Background()
+	item, err := svc.Save(ctx, &banners.Banner{Title: "Sale", Content: "Half price"}, pngFile(t, 10, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete(ctx, item.Image); err != nil {
+		t.Fatal(err)
+	}
+
+	response := testRequest(server, http.MethodGet, "/banners.image?id="+strconv.FormatInt(item.ID, 10), nil, nil)
+	if response.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", response.Code, http.StatusNotFound)
+	}
+}
+
+func TestAnonymousAccess(t *testing.T) {
+	server, svc := testServer(t)
+	if _, err := svc.Save(context.Background(), &banners.Banner{Title: "Sale", Content: "Text", Status: banners.StatusPublished}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		key    string
+		status int
+	}{
+		{"active banners", "/banners.getActive", "", http.StatusOK},
+		{"active banners with invalid key", "/banners.getActive", "wrong-key", http.StatusOK},
+		{"all banners", "/banners.getAll", "", http.StatusUnauthorized},
+		{"all banners with invalid key", "/banners.getAll", "wrong-key", http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.key != "" {
+				request.Header.Set("X-API-Key", test.key)
+			}
+			response := httptest.NewRecorder()
+			server.ServeHTTP(response, request)
+			if response.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package app
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+func (s *Server) handleServeBanner(writer http.ResponseWriter, request *http.Request) {
+	visitor, verr := visitorContext(request)
+	if verr != nil {
+		logger(request).Debug("invalid request", "error", verr)
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	}
+
+	item, err := s.bannersSvc.Pick(request.Context(), visitor)
+	if err == banners.ErrNoBanner {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	respond(writer, request, http.StatusOK, s.bannerResponse(item), nil)
+}
+
+// visitorContext describes visitor by headers and query parameters:
+// path (page where banner is shown), segment=key:value (repeated),
+// rotation (weighted or round_robin) and seed
+func visitorContext(request *http.Request) (banners.Context, *banners.ValidationError) {
+	query := request.URL.Query()
+	visitor := banners.Context{
+		Locales:  parseAcceptLanguage(request.Header.Get("Accept-Language")),
+		Device:   deviceClass(request.UserAgent()),
+		Path:     query.Get("path"),
+		Segments: make(map[string]string),
+		Rotation: banners.Rotation(query.Get("rotation")),
+	}
+	verr := &banners.ValidationError{}
+
+	for _, segment := range query["segment"] {
+		parts := strings.SplitN(segment, ":", 2)
+		if len(parts) != 2 {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "segment", Message: "must be key:value"})
+			continue
+		}
+		visitor.Segments[parts[0]] = parts[1]
+	}
+
+	switch visitor.Rotation {
+	case "", banners.RotationWeighted, banners.RotationRoundRobin:
+	default:
+		verr.Errors = append(verr.Errors, banners.FieldError{Field: "rotation", Message: "must be weighted or round_robin"})
+	}
+
+	if seed := query.Get("seed"); seed != "" {
+		value, err := strconv.ParseInt(seed, 10, 64)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "seed", Message: "must be integer"})
+		}
+		visitor.Seed = value
+	}
+
+	if len(verr.Errors) != 0 {
+		return visitor, verr
+	}
+	return visitor, nil
+}
+
+// parseAcceptLanguage returns locales ordered by quality, e.g. "ru-RU,en;q=0.8" gives [ru-RU en]
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	items := make([]weighted, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		locale := strings.TrimSpace(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				value, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					quality = value
+				}
+			}
+		}
+		if quality > 0 {
+			items = append(items, weighted{locale: locale, quality: quality})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].quality > items[j].quality
+	})
+
+	locales := make([]string, len(items))
+	for i, item := range items {
+		locales[i] = item.locale
+	}
+	return locales
+}
+
+// deviceClass guesses device of visitor by User-Agent
+func deviceClass(userAgent string) banners.Device {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		return banners.DeviceTablet
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "android"):
+		return banners.DeviceMobile
+	}
+	return banners.DeviceDesktop
+}
+
+// parseTargeting reads audience of banner: comma separated locales and devices,
+// path_prefix, comma separated key:value segments and weight
+func parseTargeting(request formValues, banner *banners.Banner, verr *banners.ValidationError) {
+	banner.Targeting.Locales = splitList(request.FormValue("locales"))
+	for _, device := range splitList(request.FormValue("devices")) {
+		banner.Targeting.Devices = append(banner.Targeting.Devices, banners.Device(device))
+	}
+	banner.Targeting.PathPrefix = request.FormValue("path_prefix")
+
+	for _, segment := range splitList(request.FormValue("segments")) {
+		parts := strings.SplitN(segment, ":", 2)
+		if len(parts) != 2 {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "segments", Message: "must be key:value list"})
+			continue
+		}
+		if banner.Targeting.Segments == nil {
+			banner.Targeting.Segments = make(map[string]string)
+		}
+		banner.Targeting.Segments[parts[0]] = parts[1]
+	}
+
+	if weight := request.FormValue("weight"); weight != "" {
+		value, err := strconv.Atoi(weight)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "weight", Message: "must be integer"})
+		}
+		banner.Weight = value
+	}
+}
+
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
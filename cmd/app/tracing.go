@@ -0,0 +1,32 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/tracing"
+)
+
+// SetTracer enables tracing of requests, requests are not traced without it
+func (s *Server) SetTracer(tracer *tracing.Tracer) {
+	s.tracer = tracer
+}
+
+// trace serves request by next within server span named by route,
+// log entries of request carry trace and span ids
+func (s *Server) trace(writer http.ResponseWriter, request *http.Request, next http.HandlerFunc) {
+	if s.tracer == nil {
+		next(writer, request)
+		return
+	}
+	s.tracer.Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		span := tracing.SpanFromContext(request.Context())
+		if _, route := s.mux.Handler(request); route != "" {
+			span.SetName(request.Method + " " + route)
+			span.SetAttribute("http.route", route)
+		}
+		sc := span.Context()
+		ctx := logging.WithContext(request.Context(), logger(request).With("trace_id", sc.TraceID, "span_id", sc.SpanID))
+		next(writer, request.WithContext(ctx))
+	})).ServeHTTP(writer, request)
+}
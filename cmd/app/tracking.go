@@ -0,0 +1,124 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// transparentGIF is 1x1 pixel returned by impression beacon
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+func (s *Server) handleClick(writer http.ResponseWriter, request *http.Request) {
+	idParam := request.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.bannersSvc.ByID(request.Context(), id)
+	if err != nil || item.Link == "" {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	s.tracker.RecordClick(request.Context(), id)
+	writer.Header().Set("Cache-Control", "no-store")
+	http.Redirect(writer, request, item.Link, http.StatusFound)
+}
+
+// handleImpression is beacon for img tag or navigator.sendBeacon
+func (s *Server) handleImpression(writer http.ResponseWriter, request *http.Request) {
+	idParam := request.URL.Query().Get("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	_, err = s.bannersSvc.ByID(request.Context(), id)
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	s.tracker.RecordImpression(request.Context(), id)
+
+	writer.Header().Set("Cache-Control", "no-store")
+	if request.Method == http.MethodPost {
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writer.Header().Set("Content-Type", "image/gif")
+	_, err = writer.Write(transparentGIF)
+	if err != nil {
+		logger(request).Warn("write response", "error", err)
+	}
+}
+
+// handleStats returns counters over [from, to) given in RFC 3339, last 24 hours by default;
+// all banners are listed unless id is set
+func (s *Server) handleStats(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	verr := &banners.ValidationError{}
+
+	to := time.Now()
+	if raw := query.Get("to"); raw != "" {
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "to", Message: "must be RFC 3339 time"})
+		}
+		to = value
+	}
+	from := to.Add(-24 * time.Hour)
+	if raw := query.Get("from"); raw != "" {
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "from", Message: "must be RFC 3339 time"})
+		}
+		from = value
+	}
+	if !to.After(from) {
+		verr.Errors = append(verr.Errors, banners.FieldError{Field: "to", Message: "must be after from"})
+	}
+
+	var id int64
+	if raw := query.Get("id"); raw != "" {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			verr.Errors = append(verr.Errors, banners.FieldError{Field: "id", Message: "must be integer"})
+		}
+		id = value
+	}
+
+	if len(verr.Errors) != 0 {
+		logger(request).Debug("invalid request", "error", verr)
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	}
+
+	var stats []banners.Stats
+	if id != 0 {
+		counters := s.tracker.Counters(request.Context(), id, from, to)
+		stats = []banners.Stats{{BannerID: id, Counters: counters, CTR: counters.CTR()}}
+	} else {
+		stats = s.tracker.Stats(request.Context(), from, to)
+	}
+
+	respond(writer, request, http.StatusOK, statsResponse{
+		From:    formatTime(from),
+		To:      formatTime(to),
+		Banners: stats,
+	}, nil)
+}
@@ -0,0 +1,460 @@
+package app
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// maxImportSize limits body of /banners.import, ZIP with images included
+const maxImportSize = 64 << 20
+
+// maxImportImagesSize limits total uncompressed size of images read from ZIP import
+const maxImportImagesSize = 256 << 20
+
+// Export and import formats
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+	formatZIP   = "zip"
+)
+
+// exportMediaTypes maps formats to media types, they are also recognized in Content-Type of import
+var exportMediaTypes = map[string]string{
+	formatJSONL: "application/x-ndjson",
+	formatCSV:   "text/csv; charset=utf-8",
+	formatZIP:   "application/zip",
+}
+
+// Files of ZIP export: banners as JSON Lines and original images under their keys
+const (
+	zipBannersFile = "banners.jsonl"
+	zipImagesDir   = "images/"
+)
+
+// csvColumns are columns of CSV export named like form fields of /banners.save,
+// lists are comma separated inside cell as in form
+var csvColumns = []string{
+	"id", "title", "content", "button", "link", "status", "priority", "starts_at", "ends_at",
+	"locales", "devices", "path_prefix", "segments", "weight", "image",
+}
+
+// bannerExport is banner as written by /banners.export and read by /banners.import
+type bannerExport struct {
+	ID int64 `json:"id"`
+	bannerRequest
+	// Image is key of image, ZIP export keeps file under zipImagesDir with that name
+	Image string `json:"image,omitempty"`
+}
+
+func exportFromBanner(item *banners.Banner) bannerExport {
+	return bannerExport{ID: item.ID, bannerRequest: *requestFromBanner(item), Image: item.Image}
+}
+
+// csvRecord is row of imported CSV by column name
+type csvRecord map[string]string
+
+// FormValue makes csvRecord readable by bannerFromForm
+func (r csvRecord) FormValue(key string) string {
+	return r[key]
+}
+
+func csvFromBanner(item *banners.Banner) []string {
+	segments := make([]string, 0, len(item.Targeting.Segments))
+	for key, value := range item.Targeting.Segments {
+		segments = append(segments, key+":"+value)
+	}
+	sort.Strings(segments)
+	devices := make([]string, len(item.Targeting.Devices))
+	for i, device := range item.Targeting.Devices {
+		devices[i] = string(device)
+	}
+
+	return []string{
+		strconv.FormatInt(item.ID, 10),
+		item.Title,
+		item.Content,
+		item.Button,
+		item.Link,
+		string(item.Status),
+		strconv.Itoa(item.Priority),
+		formatTime(item.StartsAt),
+		formatTime(item.EndsAt),
+		strings.Join(item.Targeting.Locales, ","),
+		strings.Join(devices, ","),
+		item.Targeting.PathPrefix,
+		strings.Join(segments, ","),
+		strconv.Itoa(item.Weight),
+		item.Image,
+	}
+}
+
+// handleExportBanners streams all banners as JSON Lines, CSV or ZIP with images
+func (s *Server) handleExportBanners(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format == "" {
+		format = formatJSONL
+	}
+	mediaType, ok := exportMediaTypes[format]
+	if !ok {
+		writeJSON(writer, http.StatusBadRequest, &banners.ValidationError{Errors: []banners.FieldError{
+			{Field: "format", Message: "must be one of jsonl, csv, zip"},
+		}})
+		return
+	}
+
+	items, err := s.bannersSvc.All(request.Context())
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", mediaType)
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": "banners-" + time.Now().UTC().Format("20060102T150405Z") + "." + format,
+	}))
+	switch format {
+	case formatJSONL:
+		err = writeJSONL(writer, items)
+	case formatCSV:
+		err = writeCSV(writer, items)
+	case formatZIP:
+		err = s.writeZIP(request, writer, items)
+	}
+	if err != nil {
+		// status is already sent, so client sees truncated body
+		logger(request).Warn("write response", "error", err)
+	}
+}
+
+func writeJSONL(writer io.Writer, items []*banners.Banner) error {
+	encoder := json.NewEncoder(writer)
+	for _, item := range items {
+		if err := encoder.Encode(exportFromBanner(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(writer io.Writer, items []*banners.Banner) error {
+	w := csv.NewWriter(writer)
+	if err := w.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := w.Write(csvFromBanner(item)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeZIP writes banners.jsonl, then original image of every banner
+func (s *Server) writeZIP(request *http.Request, writer io.Writer, items []*banners.Banner) error {
+	archive := zip.NewWriter(writer)
+	file, err := archive.Create(zipBannersFile)
+	if err != nil {
+		return err
+	}
+	if err = writeJSONL(file, items); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Image == "" {
+			continue
+		}
+		reader, err := s.bannersSvc.Store().Get(request.Context(), item.Image)
+		if err != nil {
+			return err
+		}
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: zipImagesDir + item.Image, Method: zip.Store})
+		if err == nil {
+			_, err = io.Copy(file, reader)
+		}
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// handleImportBanners validates and saves banners of JSON Lines, CSV or ZIP body,
+// format is given by format parameter or Content-Type
+func (s *Server) handleImportBanners(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = importFormat(request.Header.Get("Content-Type"))
+	}
+	mode := banners.ImportMode(query.Get("mode"))
+	if mode == "" {
+		mode = banners.ImportUpsert
+	}
+	dryRun := false
+	if raw := query.Get("dry_run"); raw != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(raw); err != nil {
+			writeJSON(writer, http.StatusBadRequest, &banners.ValidationError{Errors: []banners.FieldError{
+				{Field: "dry_run", Message: "must be boolean"},
+			}})
+			return
+		}
+	}
+
+	verr := &banners.ValidationError{}
+	if _, ok := exportMediaTypes[format]; !ok {
+		verr.Errors = append(verr.Errors, banners.FieldError{Field: "format", Message: "must be one of jsonl, csv, zip"})
+	}
+	if !mode.Valid() {
+		verr.Errors = append(verr.Errors, banners.FieldError{Field: "mode", Message: "must be upsert or remap"})
+	}
+	if len(verr.Errors) != 0 {
+		writeJSON(writer, http.StatusBadRequest, verr)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(writer, request.Body, maxImportSize))
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		http.Error(writer, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var rows []banners.ImportRow
+	switch format {
+	case formatJSONL:
+		rows, err = readJSONL(bytes.NewReader(body), nil)
+	case formatCSV:
+		rows, err = readCSV(bytes.NewReader(body))
+	case formatZIP:
+		rows, err = readZIP(body, s.bannersSvc.ImageSizeLimit())
+	}
+	if err != nil {
+		logger(request).Debug("invalid request", "error", err)
+		writeJSON(writer, http.StatusBadRequest, &banners.ValidationError{Errors: []banners.FieldError{
+			{Field: "body", Message: err.Error()},
+		}})
+		return
+	}
+
+	report, err := s.bannersSvc.Import(request.Context(), rows, mode, dryRun)
+	if errors.Is(err, banners.ErrImportInvalid) {
+		logger(request).Debug("invalid request", "error", err, "failed", report.Failed)
+		respond(writer, request, http.StatusBadRequest, report, nil)
+		return
+	}
+	if err != nil {
+		logger(request).Error("request failed", "error", err)
+		http.Error(writer, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	respond(writer, request, http.StatusOK, report, nil)
+}
+
+// importFormat recognizes format by media type, JSON Lines is default
+func importFormat(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return formatCSV
+	case "application/zip", "application/x-zip-compressed":
+		return formatZIP
+	}
+	return formatJSONL
+}
+
+// readJSONL reads one banner per line, lines are numbered from 1 and blank ones are skipped;
+// images are files of ZIP import and are nil otherwise
+func readJSONL(reader io.Reader, images *zipImages) ([]banners.ImportRow, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxJSONBody)
+	rows := make([]banners.ImportRow, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		row := banners.ImportRow{Row: line}
+		var item bannerExport
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&item); err != nil {
+			row.Errors = []banners.FieldError{{Field: "row", Message: jsonErrorMessage(err)}}
+			rows = append(rows, row)
+			continue
+		}
+		row.Banner = item.banner(item.ID)
+		if images != nil && item.Image != "" {
+			image, err := images.read(item.Image)
+			var verr *banners.ValidationError
+			if errors.As(err, &verr) {
+				row.Errors = verr.Errors
+			} else if err != nil {
+				return nil, err
+			}
+			row.Image = image
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// readCSV reads banners of CSV with header of csvColumns names, rows are numbered from 1 after header
+func readCSV(reader io.Reader) ([]banners.ImportRow, error) {
+	r := csv.NewReader(reader)
+	header, err := r.Read()
+	if err == io.EOF {
+		return []banners.ImportRow{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, column := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+		if !containsColumn(header[i]) {
+			return nil, errors.New("unknown column " + strconv.Quote(header[i]))
+		}
+	}
+
+	rows := make([]banners.ImportRow, 0)
+	for number := 1; ; number++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		row := banners.ImportRow{Row: number}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) && perr.Err == csv.ErrFieldCount {
+			row.Errors = []banners.FieldError{{Field: "row", Message: "must have " + strconv.Itoa(len(header)) + " columns"}}
+			rows = append(rows, row)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(csvRecord, len(header))
+		for i, column := range header {
+			values[column] = record[i]
+		}
+		var id int64
+		if raw := strings.TrimSpace(values["id"]); raw != "" {
+			if id, err = strconv.ParseInt(raw, 10, 64); err != nil {
+				row.Errors = []banners.FieldError{{Field: "id", Message: "must be integer"}}
+				rows = append(rows, row)
+				continue
+			}
+		}
+		banner, formErr := bannerFromForm(values, id)
+		if formErr != nil {
+			row.Errors = formErr.Errors
+		}
+		row.Banner = banner
+		rows = append(rows, row)
+	}
+}
+
+func containsColumn(name string) bool {
+	for _, column := range csvColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readZIP reads banners.jsonl of archive together with images it refers to,
+// every image may be at most maxImageSize bytes
+func readZIP(body []byte, maxImageSize int64) ([]banners.ImportRow, error) {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, err
+	}
+
+	var bannersFile *zip.File
+	images := &zipImages{files: make(map[string]*zip.File), maxSize: maxImageSize, remaining: maxImportImagesSize}
+	for _, file := range archive.File {
+		switch {
+		case file.Name == zipBannersFile:
+			bannersFile = file
+		case strings.HasPrefix(file.Name, zipImagesDir) && !strings.HasSuffix(file.Name, "/"):
+			images.files[strings.TrimPrefix(file.Name, zipImagesDir)] = file
+		}
+	}
+	if bannersFile == nil {
+		return nil, errors.New("archive has no " + zipBannersFile)
+	}
+
+	reader, err := bannersFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readJSONL(io.LimitReader(reader, maxImportSize), images)
+}
+
+// zipImages are image files of ZIP import by their keys, reading them is limited
+// per image and in total as sizes declared by archive cannot be trusted
+type zipImages struct {
+	files     map[string]*zip.File
+	maxSize   int64
+	remaining int64
+}
+
+// read returns image by key, missing or too large image is reported as validation error of row
+// and exceeding total size of images fails whole import
+func (images *zipImages) read(key string) ([]byte, error) {
+	file, ok := images.files[path.Clean(key)]
+	if !ok {
+		return nil, imageError("file " + zipImagesDir + key + " is missing in archive")
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	limit := images.maxSize
+	if images.remaining < limit {
+		limit = images.remaining
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > images.maxSize {
+		return nil, imageError("must be at most " + strconv.FormatInt(images.maxSize, 10) + " bytes")
+	}
+	if int64(len(data)) > images.remaining {
+		return nil, errors.New("images of archive exceed " + strconv.Itoa(maxImportImagesSize) + " bytes")
+	}
+	images.remaining -= int64(len(data))
+	return data, nil
+}
+
+func imageError(message string) error {
+	return &banners.ValidationError{Errors: []banners.FieldError{{Field: "image", Message: message}}}
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+func TestImportExportRoundTrip(t *testing.T) {
+	tests := []struct {
+		format string
+		// image is whether images travel with banners
+		image bool
+	}{
+		{formatJSONL, false},
+		{formatCSV, false},
+		{formatZIP, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			source, sourceSvc := testServer(t)
+			ctx := context.Background()
+			start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+			exported := []*banners.Banner{
+				{Title: "Sale", Content: "Half price, today only", Button: "Buy", Link: "https://shop.example/sale",
+					Status: banners.StatusPublished, Priority: 3, Weight: 2, StartsAt: start, EndsAt: start.Add(48 * time.Hour),
+					Targeting: banners.Targeting{Locales: []string{"en", "de"}, Devices: []banners.Device{banners.DeviceMobile},
+						PathPrefix: "/shop", Segments: map[string]string{"plan": "pro"}}},
+				{Title: "Quotes \"inside\"", Content: "Line with, comma"},
+			}
+			for i, item := range exported {
+				var image multipart.File
+				if i == 0 {
+					image = pngFile(t, 40, 20)
+				}
+				saved, err := sourceSvc.Save(ctx, item, image)
+				if err != nil {
+					t.Fatal(err)
+				}
+				exported[i] = saved
+			}
+
+			response := testRequest(source, http.MethodGet, "/banners.export?format="+test.format, nil, nil)
+			if response.Code != http.StatusOK {
+				t.Fatalf("export status = %d: %s", response.Code, response.Body)
+			}
+
+			target, targetSvc := testServer(t)
+			response = testRequest(target, http.MethodPost, "/banners.import?format="+test.format, response.Body, nil)
+			if response.Code != http.StatusOK {
+				t.Fatalf("import status = %d: %s", response.Code, response.Body)
+			}
+			imported, _ := targetSvc.All(ctx)
+			if len(imported) != len(exported) {
+				t.Fatalf("%d banners imported, want %d", len(imported), len(exported))
+			}
+			for i, item := range imported {
+				want := exported[i]
+				if item.ID != want.ID || item.Title != want.Title || item.Content != want.Content || item.Button != want.Button ||
+					item.Link != want.Link || item.Status != want.Status || item.Priority != want.Priority || item.Weight != want.Weight ||
+					!item.StartsAt.Equal(want.StartsAt) || !item.EndsAt.Equal(want.EndsAt) {
+					t.Errorf("imported %+v, want %+v", item, want)
+				}
+				if !reflect.DeepEqual(item.Targeting, want.Targeting) {
+					t.Errorf("imported targeting %+v, want %+v", item.Targeting, want.Targeting)
+				}
+				if hasImage := item.Image != ""; hasImage != (test.image && want.Image != "") {
+					t.Errorf("imported image %q of exported %q", item.Image, want.Image)
+				}
+				if item.Image != "" && (item.Width != want.Width || item.Height != want.Height) {
+					t.Errorf("imported image is %dx%d, want %dx%d", item.Width, item.Height, want.Width, want.Height)
+				}
+			}
+		})
+	}
+}
+
+// zipArchive returns ZIP with files of given names and contents
+func zipArchive(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, data := range files {
+		file, err := archive.Create(name)
+		if err == nil {
+			_, err = file.Write(data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestZIPImageLimits(t *testing.T) {
+	tests := []struct {
+		name string
+		// sizes of images read in order, every image may be at most 10 bytes
+		sizes     []int
+		remaining int64
+		// errors are messages of reading images, empty for image which is read
+		errors []string
+	}{
+		{"within limits", []int{10, 10}, 20, []string{"", ""}},
+		{"image too large", []int{11, 10}, 100, []string{"must be at most 10 bytes", ""}},
+		{"too large in total", []int{10, 6, 5}, 15, []string{"", "images of archive exceed 268435456 bytes", ""}},
+		{"missing image", []int{-1}, 100, []string{"file images/0.png is missing in archive"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files := make(map[string][]byte)
+			for i, size := range test.sizes {
+				if size >= 0 {
+					files[zipImagesDir+strconv.Itoa(i)+".png"] = make([]byte, size)
+				}
+			}
+			body := zipArchive(t, files)
+			archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			images := &zipImages{files: make(map[string]*zip.File), maxSize: 10, remaining: test.remaining}
+			for _, file := range archive.File {
+				images.files[strings.TrimPrefix(file.Name, zipImagesDir)] = file
+			}
+
+			var messages []string
+			for i := range test.sizes {
+				_, err := images.read(strconv.Itoa(i) + ".png")
+				message := ""
+				if err != nil {
+					message = err.Error()
+				}
+				var verr *banners.ValidationError
+				if errors.As(err, &verr) {
+					message = verr.Errors[0].Message
+				}
+				messages = append(messages, message)
+			}
+			if !reflect.DeepEqual(messages, test.errors) {
+				t.Errorf("errors = %q, want %q", messages, test.errors)
+			}
+		})
+	}
+}
+
+func TestImportZIPImageSize(t *testing.T) {
+	server, svc := testServer(t)
+	image, _ := ioutil.ReadAll(pngFile(t, 10, 10))
+
+	tests := []struct {
+		name   string
+		image  []byte
+		status int
+	}{
+		{"within service limit", image, http.StatusOK},
+		{"over service limit", make([]byte, svc.ImageSizeLimit()+1), http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := zipArchive(t, map[string][]byte{
+				zipBannersFile:         []byte(`{"title":"A","content":"a","image":"a.png"}` + "\n"),
+				zipImagesDir + "a.png": test.image,
+			})
+			response := testRequest(server, http.MethodPost, "/banners.import?format=zip", bytes.NewReader(body), nil)
+			if response.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", response.Code, test.status, response.Body)
+			}
+		})
+	}
+}
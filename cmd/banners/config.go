@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/banners"
+)
+
+// envPrefix is prefix of environment variables overriding config file
+const envPrefix = "BANNERS_"
+
+// config of server, it is read from file, then environment, then flags
+type config struct {
+	Addr            string        `json:"addr"`
+	StorageDir      string        `json:"storage_dir"`
+	StorageURL      string        `json:"storage_url"`
+	MaxImageSize    int64         `json:"max_image_size"`
+	MinFreeDisk     uint64        `json:"min_free_disk"`
+	LogLevel        string        `json:"log_level"`
+	LogFormat       string        `json:"log_format"`
+	ShutdownTimeout duration      `json:"shutdown_timeout"`
+	StatsInterval   duration      `json:"stats_interval"`
+	TLS             tlsConfig     `json:"tls"`
+	Auth            authConfig    `json:"auth"`
+	RateLimit       rateConfig    `json:"rate_limit"`
+	CORS            corsConfig    `json:"cors"`
+	Tracing         tracingConfig `json:"tracing"`
+}
+
+type tlsConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+}
+
+type authConfig struct {
+	// APIKeys maps key to its principal
+	APIKeys    map[string]principalConfig `json:"api_keys"`
+	JWTSecret  string                     `json:"jwt_secret"`
+	BasicUsers map[string]basicConfig     `json:"basic_users"`
+}
+
+type principalConfig struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type basicConfig struct {
+	Password string `json:"password"`
+	Role     string `json:"role"`
+}
+
+type rateConfig struct {
+	// Rate is requests per second, limiting is off when it is 0
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	// Key is ip, principal or route
+	Key string `json:"key"`
+	// TrustedProxies is number of proxies appending to X-Forwarded-For in front of server
+	TrustedProxies int `json:"trusted_proxies"`
+}
+
+type corsConfig struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           duration `json:"max_age"`
+}
+
+type tracingConfig struct {
+	// Endpoint is OTLP/HTTP traces URL like http://localhost:4318/v1/traces, tracing is off when it is empty
+	Endpoint    string            `json:"endpoint"`
+	ServiceName string            `json:"service_name"`
+	Headers     map[string]string `json:"headers"`
+	// Interval is how often spans are exported
+	Interval duration `json:"interval"`
+}
+
+// duration is time.Duration written like "30s" in config file
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("duration must be string like 30s")
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+var (
+	logLevels  = []string{"debug", "info", "warn", "error"}
+	logFormats = []string{"logfmt", "json"}
+)
+
+func defaultConfig() *config {
+	return &config{
+		Addr:            "0.0.0.0:9999",
+		StorageDir:      banners.STORAGE,
+		StorageURL:      "/web/banners",
+		MaxImageSize:    banners.MaxImageSize,
+		MinFreeDisk:     100 << 20,
+		LogLevel:        "info",
+		LogFormat:       "logfmt",
+		ShutdownTimeout: duration(15 * time.Second),
+		StatsInterval:   duration(time.Minute),
+		RateLimit:       rateConfig{Burst: 20, Key: "ip"},
+		Tracing:         tracingConfig{ServiceName: "banners", Interval: duration(5 * time.Second)},
+	}
+}
+
+// loadConfig builds config from defaults, file, environment and flags, in that order of precedence;
+// checkOnly reports whether -check-config was given
+func loadConfig(args []string) (cfg *config, checkOnly bool, err error) {
+	cfg = defaultConfig()
+
+	flags := flag.NewFlagSet("banners", flag.ContinueOnError)
+	configPath := flags.String("config", os.Getenv(envPrefix+"CONFIG"), "path to YAML or JSON config file")
+	check := flags.Bool("check-config", false, "validate configuration and exit")
+	addr := flags.String("addr", cfg.Addr, "listen address")
+	storageDir := flags.String("storage-dir", cfg.StorageDir, "directory of banner images and stats")
+	certFile := flags.String("tls-cert", "", "TLS certificate file")
+	keyFile := flags.String("tls-key", "", "TLS key file")
+	logLevel := flags.String("log-level", cfg.LogLevel, "log level: "+strings.Join(logLevels, ", "))
+	logFormat := flags.String("log-format", cfg.LogFormat, "log format: "+strings.Join(logFormats, ", "))
+	if err = flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if *configPath != "" {
+		if err = cfg.readFile(*configPath); err != nil {
+			return nil, false, err
+		}
+	}
+	if err = cfg.readEnv(); err != nil {
+		return nil, false, err
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "storage-dir":
+			cfg.StorageDir = *storageDir
+		case "tls-cert":
+			cfg.TLS.CertFile = *certFile
+		case "tls-key":
+			cfg.TLS.KeyFile = *keyFile
+		case "log-level":
+			cfg.LogLevel = *logLevel
+		case "log-format":
+			cfg.LogFormat = *logFormat
+		}
+	})
+	return cfg, *check, nil
+}
+
+// readFile reads JSON or, by .yaml or .yml extension, YAML config
+func (c *config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		value, err := parseYAML(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(value); err != nil {
+			return err
+		}
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(c); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// readEnv applies BANNERS_* variables
+func (c *config) readEnv() error {
+	for name, target := range map[string]*string{
+		"ADDR":             &c.Addr,
+		"STORAGE_DIR":      &c.StorageDir,
+		"STORAGE_URL":      &c.StorageURL,
+		"LOG_LEVEL":        &c.LogLevel,
+		"LOG_FORMAT":       &c.LogFormat,
+		"TLS_CERT":         &c.TLS.CertFile,
+		"TLS_KEY":          &c.TLS.KeyFile,
+		"JWT_SECRET":       &c.Auth.JWTSecret,
+		"TRACING_ENDPOINT": &c.Tracing.Endpoint,
+	} {
+		if value, ok := os.LookupEnv(envPrefix + name); ok {
+			*target = value
+		}
+	}
+
+	if value, ok := os.LookupEnv(envPrefix + "SHUTDOWN_TIMEOUT"); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%sSHUTDOWN_TIMEOUT: %w", envPrefix, err)
+		}
+		c.ShutdownTimeout = duration(parsed)
+	}
+	return nil
+}
+
+// validate returns all problems of config at once
+func (c *config) validate() error {
+	problems := make([]string, 0)
+	if c.Addr == "" {
+		problems = append(problems, "addr is required")
+	}
+	if c.StorageDir == "" {
+		problems = append(problems, "storage_dir is required")
+	}
+	if !strings.HasPrefix(c.StorageURL, "/") {
+		problems = append(problems, "storage_url must start with /")
+	}
+	if c.MaxImageSize <= 0 {
+		problems = append(problems, "max_image_size must be positive")
+	}
+	if !containsString(logLevels, c.LogLevel) {
+		problems = append(problems, "log_level must be one of "+strings.Join(logLevels, ", "))
+	}
+	if !containsString(logFormats, c.LogFormat) {
+		problems = append(problems, "log_format must be one of "+strings.Join(logFormats, ", "))
+	}
+	if c.ShutdownTimeout <= 0 || c.StatsInterval <= 0 {
+		problems = append(problems, "shutdown_timeout and stats_interval must be positive")
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		problems = append(problems, "tls cert_file and key_file must be set together")
+	}
+	for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			problems = append(problems, "tls: "+err.Error())
+		}
+	}
+	for key, principal := range c.Auth.APIKeys {
+		if _, ok := auth.ParseRole(principal.Role); !ok || principal.Name == "" {
+			problems = append(problems, "auth api key "+maskSecret(key)+" must have name and role viewer, editor or admin")
+		}
+	}
+	for name, user := range c.Auth.BasicUsers {
+		if _, ok := auth.ParseRole(user.Role); !ok || user.Password == "" {
+			problems = append(problems, "auth basic user "+name+" must have password and role viewer, editor or admin")
+		}
+	}
+	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+		problems = append(problems, "auth jwt_secret must be at least 32 bytes")
+	}
+	if c.RateLimit.Rate < 0 || (c.RateLimit.Rate > 0 && c.RateLimit.Burst < 1) {
+		problems = append(problems, "rate_limit rate must not be negative and burst must be positive")
+	}
+	if !containsString([]string{"ip", "principal", "route"}, c.RateLimit.Key) {
+		problems = append(problems, "rate_limit key must be ip, principal or route")
+	}
+	if c.RateLimit.TrustedProxies < 0 {
+		problems = append(problems, "rate_limit trusted_proxies must not be negative")
+	}
+
+	if c.Tracing.Endpoint != "" {
+		endpoint, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			problems = append(problems, "tracing endpoint must be http or https URL")
+		}
+		if c.Tracing.ServiceName == "" || c.Tracing.Interval <= 0 {
+			problems = append(problems, "tracing service_name is required and interval must be positive")
+		}
+	}
+
+	if len(problems) != 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSecret keeps only beginning of secret for messages
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+	return secret[:4] + "****"
+}
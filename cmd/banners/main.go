@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/MrHakimov/http/cmd/app"
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/banners"
+	"github.com/MrHakimov/http/pkg/cors"
+	"github.com/MrHakimov/http/pkg/health"
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/ratelimit"
+	"github.com/MrHakimov/http/pkg/storage"
+	"github.com/MrHakimov/http/pkg/tracing"
+)
+
+func main() {
+	cfg, checkOnly, err := loadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err = cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if checkOnly {
+		fmt.Println("configuration is valid")
+		return
+	}
+
+	logger := newLogger(cfg)
+	logging.SetDefault(logger)
+	if err = execute(cfg, logger); err != nil {
+		logger.Error("server stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// newLogger writes entries of configured level and format to stderr
+func newLogger(cfg *config) *logging.Logger {
+	level, _ := logging.ParseLevel(cfg.LogLevel)
+	format, _ := logging.ParseFormat(cfg.LogFormat)
+	return logging.New(os.Stderr, level, format)
+}
+
+// execute serves until SIGINT or SIGTERM, then waits for running requests and flushes stats
+func execute(cfg *config, logger *logging.Logger) error {
+	mux := http.NewServeMux()
+	store := storage.NewFileStore(cfg.StorageDir, cfg.StorageURL)
+	bannersSvc := banners.NewService(store, cfg.MaxImageSize)
+	server := app.NewServer(mux, bannersSvc)
+	server.SetLogger(logger)
+	authn := authenticator(cfg.Auth)
+	server.SetAuthenticator(authn)
+	server.Health().Register("storage", health.Writable(cfg.StorageDir))
+	server.Health().Register("disk", health.DiskSpace(cfg.StorageDir, cfg.MinFreeDisk))
+	server.Init()
+	mux.Handle(cfg.StorageURL+"/", http.StripPrefix(cfg.StorageURL, images(cfg.StorageDir)))
+
+	if cfg.RateLimit.Rate > 0 {
+		limiter := ratelimit.NewLimiter(nil, ratelimit.Limit{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst}, rateKey(cfg.RateLimit.Key))
+		limiter.TrustedProxies = cfg.RateLimit.TrustedProxies
+		limiter.Authenticator = authn
+		server.Use(limiter.Handler)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 0 {
+		server.Use(cors.New(cors.Config{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           time.Duration(cfg.CORS.MaxAge),
+		}).Handler)
+	}
+
+	ctx, cancel := context.WithCancel(logging.WithContext(context.Background(), logger))
+	defer cancel()
+
+	tracker := server.Tracker()
+	if err := tracker.Load(ctx, time.Now().AddDate(0, 0, -30), time.Now()); err != nil {
+		logger.Error("load stats", "error", err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		tracker.Run(ctx, time.Duration(cfg.StatsInterval))
+	}()
+	if cfg.Tracing.Endpoint != "" {
+		exporter := tracing.NewOTLPExporter(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName)
+		exporter.Headers = cfg.Tracing.Headers
+		tracer := tracing.NewTracer(cfg.Tracing.ServiceName, exporter)
+		server.SetTracer(tracer)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tracer.Run(ctx, time.Duration(cfg.Tracing.Interval))
+		}()
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	logger.Info("listening", "addr", listener.Addr(), "tls", cfg.TLS.CertFile != "")
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.TLS.CertFile != "" {
+			serveErr <- srv.ServeTLS(listener, cfg.TLS.CertFile, cfg.TLS.KeyFile)
+			return
+		}
+		serveErr <- srv.Serve(listener)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err = <-serveErr:
+		cancel()
+		wg.Wait()
+		return err
+	case sig := <-signals:
+		logger.Info("shutting down", "signal", sig)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer shutdownCancel()
+	err = srv.Shutdown(shutdownCtx)
+	cancel()
+	wg.Wait()
+	return err
+}
+
+// images serves banner images of storage directory, but neither stats nor directory listings
+func images(dir string) http.Handler {
+	files := http.FileServer(http.Dir(dir))
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if strings.HasSuffix(request.URL.Path, "/") || strings.HasPrefix(path.Clean(request.URL.Path), "/stats/") {
+			http.NotFound(writer, request)
+			return
+		}
+		files.ServeHTTP(writer, request)
+	})
+}
+
+// authenticator chains configured API keys, JWT and Basic users
+func authenticator(cfg authConfig) auth.Authenticator {
+	chain := auth.Chain{}
+	if len(cfg.APIKeys) != 0 {
+		keys := make(map[string]auth.Principal, len(cfg.APIKeys))
+		for key, principal := range cfg.APIKeys {
+			role, _ := auth.ParseRole(principal.Role)
+			keys[key] = auth.Principal{Name: principal.Name, Role: role}
+		}
+		chain = append(chain, auth.NewAPIKeys(keys))
+	}
+	if cfg.JWTSecret != "" {
+		chain = append(chain, auth.NewJWT([]byte(cfg.JWTSecret)))
+	}
+	if len(cfg.BasicUsers) != 0 {
+		users := make(map[string]auth.BasicUser, len(cfg.BasicUsers))
+		for name, user := range cfg.BasicUsers {
+			role, _ := auth.ParseRole(user.Role)
+			users[name] = auth.BasicUser{Password: user.Password, Role: role}
+		}
+		chain = append(chain, auth.NewBasic(users))
+	}
+	return chain
+}
+
+func rateKey(name string) ratelimit.KeyFunc {
+	switch name {
+	case "principal":
+		return ratelimit.ByPrincipal
+	case "route":
+		return ratelimit.ByRoute
+	}
+	return ratelimit.ByIP
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads subset of YAML enough for config files: nested block mappings,
+// block lists (of scalars or mappings), flow lists of scalars, quoted and plain scalars, comments
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimRight(stripComment(raw), " ")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(text) - len(trimmed), text: trimmed, number: i + 1})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	value, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return value, nil
+}
+
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// errorf describes problem of current line
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return lineErrorf(p.lines[p.pos].number, format, args...)
+}
+
+func lineErrorf(number int, format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: "+format, append([]interface{}{number}, args...)...)
+}
+
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	if isListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		line := p.lines[p.pos]
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok || isListItem(line.text) {
+			return nil, p.errorf("expected key: value")
+		}
+		if _, exists := result[key]; exists {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		p.pos++
+
+		value, err := p.value(indent, rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return result, nil
+}
+
+// value parses rest of line number after key or, when it is empty, nested block
+func (p *yamlParser) value(indent int, rest string, number int) (interface{}, error) {
+	if rest != "" {
+		value, err := yamlScalar(rest)
+		if err != nil {
+			return nil, lineErrorf(number, "%v", err)
+		}
+		return value, nil
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return p.block(p.lines[p.pos].indent)
+	}
+	// list may be indented same as its key
+	if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+		return p.list(indent)
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) list(indent int) ([]interface{}, error) {
+	result := make([]interface{}, 0)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		item := strings.TrimLeft(line.text[1:], " ")
+		if item == "" {
+			p.pos++
+			value, err := p.value(indent, "", line.number)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		if _, _, ok := splitYAMLKey(item); ok {
+			// mapping starting on line of dash continues on lines indented as its first key
+			offset := indent + len(line.text) - len(item)
+			p.lines[p.pos] = yamlLine{indent: offset, text: item, number: line.number}
+			value, err := p.mapping(offset)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		value, err := yamlScalar(item)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		result = append(result, value)
+		p.pos++
+	}
+	return result, nil
+}
+
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" into key and value
+func splitYAMLKey(text string) (string, string, bool) {
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		end := strings.Index(text[1:], text[:1]+":")
+		if end == -1 {
+			return "", "", false
+		}
+		key, err := yamlScalar(text[:end+2])
+		if err != nil {
+			return "", "", false
+		}
+		return fmt.Sprint(key), strings.TrimSpace(text[end+3:]), true
+	}
+
+	if strings.HasSuffix(text, ":") {
+		return text[:len(text)-1], "", true
+	}
+	index := strings.Index(text, ": ")
+	if index <= 0 {
+		return "", "", false
+	}
+	return text[:index], strings.TrimSpace(text[index+2:]), true
+}
+
+// stripComment removes # comment which is not inside quoted scalar; quote opens scalar
+// only at its beginning, so apostrophe of plain scalar like it's #1 is not a quote
+func stripComment(line string) string {
+	var quote byte
+	flow := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote == '\'' && c == '\'' && i+1 < len(line) && line[i+1] == '\'':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && scalarStart(line[:i], flow > 0):
+			quote = c
+		case c == '[' && (flow > 0 || scalarStart(line[:i], false)):
+			flow++
+		case c == ']' && flow > 0:
+			flow--
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// scalarStart reports whether scalar may start right after text: at beginning of line,
+// after "key: " or "- " of list item and, inside flow list, after "[" or ","
+func scalarStart(text string, flow bool) bool {
+	trimmed := strings.TrimRight(text, " ")
+	spaced := len(trimmed) < len(text)
+	switch {
+	case strings.TrimLeft(trimmed, " ") == "":
+		return true
+	case flow:
+		return strings.HasSuffix(trimmed, "[") || strings.HasSuffix(trimmed, ",")
+	case strings.HasSuffix(trimmed, ":") || strings.TrimLeft(trimmed, " ") == "-":
+		return spaced
+	}
+	return false
+}
+
+func yamlScalar(text string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		return strconv.Unquote(text)
+	case strings.HasPrefix(text, "'"):
+		if len(text) < 2 || !strings.HasSuffix(text, "'") {
+			return nil, fmt.Errorf("unterminated string %s", text)
+		}
+		return strings.Replace(text[1:len(text)-1], "''", "'", -1), nil
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("unterminated list %s", text)
+		}
+		items := make([]interface{}, 0)
+		inner := strings.TrimSpace(text[1 : len(text)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, part := range splitFlow(inner) {
+			item, err := yamlScalar(strings.TrimSpace(part))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case text == "{}":
+		return map[string]interface{}{}, nil
+	}
+
+	switch text {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if value, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return value, nil
+	}
+	if strings.ContainsAny(text, "0123456789") {
+		if value, err := strconv.ParseFloat(text, 64); err == nil {
+			return value, nil
+		}
+	}
+	return text, nil
+}
+
+// splitFlow splits items of flow list by commas which are not inside quotes
+func splitFlow(inner string) []string {
+	var parts []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(inner); i++ {
+		switch c := inner[i]; {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && strings.TrimSpace(inner[start:i]) == "":
+			quote = c
+		case c == ',':
+			parts = append(parts, inner[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, inner[start:])
+}
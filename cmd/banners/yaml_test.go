@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	type object = map[string]interface{}
+	type list = []interface{}
+
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+	}{
+		{"empty", "# nothing here\n---\n", object{}},
+		{"scalars", "port: 8080\nratio: 0.5\nenabled: true\nname: banners\nnothing: ~",
+			object{"port": int64(8080), "ratio": 0.5, "enabled": true, "name": "banners", "nothing": nil}},
+		{"apostrophe in plain scalar", "title: it's #1", object{"title": "it's"}},
+		{"apostrophe before comment", "title: it's here # note", object{"title": "it's here"}},
+		{"hash inside word", "color: red#1", object{"color": "red#1"}},
+		{"hash in double quotes", `title: "# not a comment" # comment`, object{"title": "# not a comment"}},
+		{"escaped double quote", `title: "say \"hi\" # x" # comment`, object{"title": `say "hi" # x`}},
+		{"hash in single quotes", "title: 'it''s #1' # comment", object{"title": "it's #1"}},
+		{"quoted key", `"a: b": 1`, object{"a: b": int64(1)}},
+		{"quoted number", `port: "8080"`, object{"port": "8080"}},
+		{"nested mapping", "server:\n  addr: :9999\n  tls:\n    cert: a.pem\n",
+			object{"server": object{"addr": ":9999", "tls": object{"cert": "a.pem"}}}},
+		{"block list", "origins:\n  - https://a.example\n  - 'https://b.example' # second\n",
+			object{"origins": list{"https://a.example", "https://b.example"}}},
+		{"list at key indent", "origins:\n- a\n- b\nport: 1",
+			object{"origins": list{"a", "b"}, "port": int64(1)}},
+		{"list of mappings", "keys:\n  - name: ci\n    role: editor\n  - name: 'bob''s'\n    role: viewer\n",
+			object{"keys": list{object{"name": "ci", "role": "editor"}, object{"name": "bob's", "role": "viewer"}}}},
+		{"flow list", "devices: [mobile, 'tablet, big', \"desk#top\"] # comment",
+			object{"devices": list{"mobile", "tablet, big", "desk#top"}}},
+		{"empty flow list and mapping", "a: []\nb: {}", object{"a": list{}, "b": object{}}},
+		{"windows line endings", "a: 1\r\nb: it's\r\n", object{"a": int64(1), "b": "it's"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := parseYAML([]byte(test.input))
+			if err != nil {
+				t.Fatalf("parseYAML() = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"tab indentation", "server:\n\taddr: x", "line 2: tabs are not allowed"},
+		{"missing colon", "port: 1\njust text", "line 2: expected key: value"},
+		{"duplicate key", "a: 1\n# comment\na: 2", "line 3: duplicate key \"a\""},
+		{"unterminated single quote", "a: 1\nb: 'open # comment", "line 2: unterminated string"},
+		{"unterminated flow list", "a: [1, 2", "line 1: unterminated list"},
+		{"bad escape", `a: "\q"`, "line 1:"},
+		{"unexpected indentation", "a: 1\n    b: 2", "line 2: unexpected indentation"},
+		{"list item in mapping", "a: 1\n- b", "line 2: expected key: value"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseYAML([]byte(test.input))
+			if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+				t.Errorf("parseYAML() = %v, want error starting with %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// APIKeyHeader carries static API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys authenticates by static keys from X-API-Key header
+type APIKeys struct {
+	keys map[[sha256.Size]byte]Principal
+}
+
+// NewAPIKeys creates authenticator of given key to principal mapping
+func NewAPIKeys(keys map[string]Principal) *APIKeys {
+	hashed := make(map[[sha256.Size]byte]Principal, len(keys))
+	for key, principal := range keys {
+		hashed[sha256.Sum256([]byte(key))] = principal
+	}
+	return &APIKeys{keys: hashed}
+}
+
+// Authenticate implements Authenticator, keys are compared by hash so lookup time does not leak them
+func (a *APIKeys) Authenticate(request *http.Request) (*Principal, error) {
+	key := request.Header.Get(APIKeyHeader)
+	if key == "" {
+		return nil, nil
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	for hash, principal := range a.keys {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			principal := principal
+			return &principal, nil
+		}
+	}
+	return nil, ErrInvalidCredentials
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidCredentials is returned when credentials are present but wrong, expired or malformed
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Role is level of access, every role includes rights of lower ones
+type Role int
+
+// Supported roles, RoleNone marks public routes
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleEditor
+	RoleAdmin
+)
+
+var roleNames = map[Role]string{
+	RoleNone:   "none",
+	RoleViewer: "viewer",
+	RoleEditor: "editor",
+	RoleAdmin:  "admin",
+}
+
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseRole returns role by name like "editor"
+func ParseRole(name string) (Role, bool) {
+	for role, roleName := range roleNames {
+		if roleName == name && role != RoleNone {
+			return role, true
+		}
+	}
+	return RoleNone, false
+}
+
+// Principal is authenticated caller
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// Authenticator identifies caller of request,
+// it returns nil principal and nil error when request carries no credentials it understands
+type Authenticator interface {
+	Authenticate(request *http.Request) (*Principal, error)
+}
+
+// Chain tries authenticators in order, first one recognizing credentials wins
+type Chain []Authenticator
+
+// Authenticate implements Authenticator
+func (c Chain) Authenticate(request *http.Request) (*Principal, error) {
+	for _, authenticator := range c {
+		principal, err := authenticator.Authenticate(request)
+		if err != nil || principal != nil {
+			return principal, err
+		}
+	}
+	return nil, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns context carrying principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// FromContext returns principal stored by WithPrincipal or nil
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// Authorize authenticates request and checks that caller has at least given role.
+// It returns request with principal in context and status, which is 200 on success,
+// 401 for missing or invalid credentials and 403 for insufficient role.
+// Public routes (RoleNone) are always allowed, principal is attached when credentials are valid.
+func Authorize(authenticator Authenticator, role Role, request *http.Request) (*http.Request, int) {
+	var principal *Principal
+	var err error
+	if authenticator != nil {
+		principal, err = authenticator.Authenticate(request)
+	}
+	if principal != nil && err == nil {
+		request = request.WithContext(WithPrincipal(request.Context(), principal))
+	}
+
+	switch {
+	case role == RoleNone:
+		return request, http.StatusOK
+	case err != nil || principal == nil:
+		return request, http.StatusUnauthorized
+	case principal.Role < role:
+		return request, http.StatusForbidden
+	}
+	return request, http.StatusOK
+}
+
+// Require wraps handler, so it is called only by callers having role
+func Require(authenticator Authenticator, role Role, handler http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		request, status := Authorize(authenticator, role, request)
+		if status != http.StatusOK {
+			Deny(writer, status)
+			return
+		}
+		handler(writer, request)
+	}
+}
+
+// Deny writes 401 with challenges of supported schemes or 403
+func Deny(writer http.ResponseWriter, status int) {
+	if status == http.StatusUnauthorized {
+		writer.Header().Add("WWW-Authenticate", `Bearer realm="banners"`)
+		writer.Header().Add("WWW-Authenticate", `Basic realm="banners", charset="UTF-8"`)
+	}
+	http.Error(writer, http.StatusText(status), status)
+}
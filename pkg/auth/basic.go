@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+)
+
+// BasicUser is account of HTTP Basic authentication
+type BasicUser struct {
+	Password string
+	Role     Role
+}
+
+// Basic authenticates by HTTP Basic credentials, meant for admin UI
+type Basic struct {
+	users map[string]basicAccount
+}
+
+type basicAccount struct {
+	password [sha256.Size]byte
+	role     Role
+}
+
+// NewBasic creates authenticator of given user name to account mapping
+func NewBasic(users map[string]BasicUser) *Basic {
+	accounts := make(map[string]basicAccount, len(users))
+	for name, user := range users {
+		accounts[name] = basicAccount{password: sha256.Sum256([]byte(user.Password)), role: user.Role}
+	}
+	return &Basic{users: accounts}
+}
+
+// Authenticate implements Authenticator
+func (b *Basic) Authenticate(request *http.Request) (*Principal, error) {
+	name, password, ok := request.BasicAuth()
+	if !ok {
+		return nil, nil
+	}
+
+	account, ok := b.users[name]
+	sum := sha256.Sum256([]byte(password))
+	if subtle.ConstantTimeCompare(account.password[:], sum[:]) != 1 || !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: name, Role: account.role}, nil
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWT authenticates by bearer tokens signed with HS256,
+// token carries caller in sub claim and its role in role claim
+type JWT struct {
+	secret []byte
+	// Leeway is tolerated clock skew for exp and nbf
+	Leeway time.Duration
+	now    func() time.Time
+}
+
+// Claims are claims of token understood by JWT
+type Claims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	NotBefore int64  `json:"nbf,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// NewJWT creates authenticator of tokens signed with secret
+func NewJWT(secret []byte) *JWT {
+	return &JWT{secret: secret, Leeway: time.Minute, now: time.Now}
+}
+
+// Authenticate implements Authenticator
+func (j *JWT) Authenticate(request *http.Request) (*Principal, error) {
+	header := request.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return nil, nil
+	}
+
+	claims, err := j.Verify(strings.TrimSpace(header[7:]))
+	if err != nil {
+		return nil, err
+	}
+	role, ok := ParseRole(claims.Role)
+	if !ok || claims.Subject == "" {
+		return nil, ErrInvalidCredentials
+	}
+	return &Principal{Name: claims.Subject, Role: role}, nil
+}
+
+// Verify checks signature and validity period of token and returns its claims,
+// token without exp claim is rejected, so leaked token does not stay valid forever
+func (j *JWT) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidCredentials
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return nil, ErrInvalidCredentials
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, j.sign(parts[0]+"."+parts[1])) {
+		return nil, ErrInvalidCredentials
+	}
+
+	var claims Claims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	now := j.now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(j.Leeway)) {
+		return nil, ErrInvalidCredentials
+	}
+	if claims.NotBefore != 0 && now.Before(time.Unix(claims.NotBefore, 0).Add(-j.Leeway)) {
+		return nil, ErrInvalidCredentials
+	}
+	return &claims, nil
+}
+
+// Issue signs token for subject with role valid for ttl
+func (j *JWT) Issue(subject string, role Role, ttl time.Duration) (string, error) {
+	now := j.now()
+	header, err := json.Marshal(jwtHeader{Algorithm: "HS256", Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(Claims{
+		Subject:   subject,
+		Role:      role.String(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		IssuedAt:  now.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(j.sign(unsigned)), nil
+}
+
+func (j *JWT) sign(unsigned string) []byte {
+	mac := hmac.New(sha256.New, j.secret)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, value)
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// signed returns token of claims signed by j, header is HS256 unless given
+func signed(t *testing.T, j *JWT, claims interface{}, header string) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header == "" {
+		header = `{"alg":"HS256","typ":"JWT"}`
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(j.sign(unsigned))
+}
+
+func TestJWTVerify(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	j := NewJWT([]byte("secret"))
+	j.now = func() time.Time { return now }
+	other := NewJWT([]byte("other"))
+	at := func(offset time.Duration) int64 { return now.Add(offset).Unix() }
+
+	issued, err := j.Issue("alice", RoleEditor, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"issued", issued, true},
+		{"valid", signed(t, j, Claims{Subject: "a", Role: "viewer", ExpiresAt: at(time.Hour)}, ""), true},
+		{"without exp", signed(t, j, Claims{Subject: "a", Role: "viewer"}, ""), false},
+		{"expired", signed(t, j, Claims{Subject: "a", ExpiresAt: at(-time.Hour)}, ""), false},
+		{"expired within leeway", signed(t, j, Claims{Subject: "a", ExpiresAt: at(-30 * time.Second)}, ""), true},
+		{"not yet valid", signed(t, j, Claims{Subject: "a", ExpiresAt: at(2 * time.Hour), NotBefore: at(time.Hour)}, ""), false},
+		{"not yet valid within leeway", signed(t, j, Claims{Subject: "a", ExpiresAt: at(time.Hour), NotBefore: at(30 * time.Second)}, ""), true},
+		{"other secret", signed(t, other, Claims{Subject: "a", ExpiresAt: at(time.Hour)}, ""), false},
+		{"alg none", signed(t, j, Claims{Subject: "a", ExpiresAt: at(time.Hour)}, `{"alg":"none"}`), false},
+		{"exp of wrong type", signed(t, j, map[string]string{"sub": "a", "exp": "tomorrow"}, ""), false},
+		{"two parts", "a.b", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := j.Verify(test.token)
+			if test.valid && err != nil {
+				t.Errorf("Verify() = %v, want valid", err)
+			}
+			if !test.valid && err != ErrInvalidCredentials {
+				t.Errorf("Verify() = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticate(t *testing.T) {
+	j := NewJWT([]byte("secret"))
+	token, err := j.Issue("alice", RoleEditor, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		header    string
+		principal *Principal
+		err       error
+	}{
+		{"bearer", "Bearer " + token, &Principal{Name: "alice", Role: RoleEditor}, nil},
+		{"lower case scheme", "bearer " + token, &Principal{Name: "alice", Role: RoleEditor}, nil},
+		{"other scheme", "Basic YTpi", nil, nil},
+		{"missing", "", nil, nil},
+		{"broken token", "Bearer x.y.z", nil, ErrInvalidCredentials},
+		{"unknown role", "Bearer " + signed(t, j, Claims{Subject: "a", Role: "root", ExpiresAt: time.Now().Add(time.Hour).Unix()}, ""), nil, ErrInvalidCredentials},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+			principal, err := j.Authenticate(request)
+			if err != test.err {
+				t.Fatalf("Authenticate() error = %v, want %v", err, test.err)
+			}
+			if (principal == nil) != (test.principal == nil) || principal != nil && *principal != *test.principal {
+				t.Errorf("Authenticate() = %+v, want %+v", principal, test.principal)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package banners
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBatchFailed is returned by atomic batch when some operation fails, nothing is changed then
+var ErrBatchFailed = errors.New("batch has failed operations")
+
+// ErrBatchAction is error of operation with action other than create, update or delete
+var ErrBatchAction = errors.New("batch action must be create, update or delete")
+
+// ErrBatchDuplicate is error of atomic batch operation changing banner changed by earlier operation
+var ErrBatchDuplicate = errors.New("banner is changed by another operation of batch")
+
+// BatchOp is one change of batch
+type BatchOp struct {
+	// Action is ActionCreate, ActionUpdate or ActionDelete
+	Action Action
+	// Banner is created or updated one, update must carry current revision
+	Banner *Banner
+	// ID is banner deleted by ActionDelete
+	ID int64
+}
+
+// BatchResult is outcome of one operation, Banner is created, updated or deleted one
+type BatchResult struct {
+	Banner *Banner
+	Err    error
+}
+
+// Batch applies operations in order under one lock. Atomic batch checks all of them first
+// and applies none when some fails, it returns ErrBatchFailed and results with errors of failed
+// operations then. Otherwise each operation succeeds or fails on its own.
+func (s *Service) Batch(ctx context.Context, ops []BatchOp, atomic bool) (results []BatchResult, err error) {
+	ctx, end := s.begin(ctx, "batch")
+	defer func() { end(err) }()
+	return s.batch(ctx, ops, atomic)
+}
+
+// SaveMany creates (id is 0) or updates banners like Save without images, see Batch
+func (s *Service) SaveMany(ctx context.Context, items []*Banner, atomic bool) (results []BatchResult, err error) {
+	ctx, end := s.begin(ctx, "save_many")
+	defer func() { end(err) }()
+	ops := make([]BatchOp, len(items))
+	for i, item := range items {
+		ops[i] = BatchOp{Action: ActionUpdate, Banner: item}
+		if item.ID == 0 {
+			ops[i].Action = ActionCreate
+		}
+	}
+	return s.batch(ctx, ops, atomic)
+}
+
+// RemoveMany soft deletes banners like RemoveByID, see Batch
+func (s *Service) RemoveMany(ctx context.Context, ids []int64, atomic bool) (results []BatchResult, err error) {
+	ctx, end := s.begin(ctx, "remove_many")
+	defer func() { end(err) }()
+	ops := make([]BatchOp, len(ids))
+	for i, id := range ids {
+		ops[i] = BatchOp{Action: ActionDelete, ID: id}
+	}
+	return s.batch(ctx, ops, atomic)
+}
+
+func (s *Service) batch(ctx context.Context, ops []BatchOp, atomic bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if atomic {
+		failed := false
+		seen := make(map[int64]bool)
+		for i, op := range ops {
+			_, err := s.checkOp(op)
+			id := op.ID
+			if op.Banner != nil {
+				id = op.Banner.ID
+			}
+			if err == nil && id != 0 && seen[id] {
+				err = ErrBatchDuplicate
+			}
+			seen[id] = true
+			if err != nil {
+				results[i].Err = err
+				failed = true
+			}
+		}
+		if failed {
+			return results, ErrBatchFailed
+		}
+	}
+
+	for i, op := range ops {
+		results[i].Banner, results[i].Err = s.applyOp(ctx, op)
+	}
+	return results, nil
+}
+
+// checkOp returns index of changed banner or error of operation; must be called under lock
+func (s *Service) checkOp(op BatchOp) (int, error) {
+	switch op.Action {
+	case ActionCreate, ActionUpdate:
+		if op.Banner == nil {
+			return -1, &ValidationError{Errors: []FieldError{{Field: "banner", Message: "is required"}}}
+		}
+		item := op.Banner
+		if item.Status == "" {
+			item.Status = StatusDraft
+		}
+		if err := Validate(item); err != nil {
+			return -1, err
+		}
+		if op.Action == ActionCreate {
+			if item.ID != 0 {
+				return -1, &ValidationError{Errors: []FieldError{{Field: "id", Message: "must be 0 to create"}}}
+			}
+			return -1, nil
+		}
+		index := s.indexOf(item.ID)
+		if index == -1 {
+			return -1, ErrNotFound
+		}
+		if item.Revision != s.items[index].Revision {
+			return -1, ErrConflict
+		}
+		return index, nil
+	case ActionDelete:
+		index := s.indexOf(op.ID)
+		if index == -1 {
+			return -1, ErrNotFound
+		}
+		return index, nil
+	}
+	return -1, ErrBatchAction
+}
+
+// applyOp checks and applies operation; must be called under lock
+func (s *Service) applyOp(ctx context.Context, op BatchOp) (*Banner, error) {
+	index, err := s.checkOp(op)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Action {
+	case ActionCreate:
+		return s.create(ctx, op.Banner, nil), nil
+	case ActionUpdate:
+		item, _ := s.update(ctx, index, op.Banner, nil)
+		return item, nil
+	}
+	return s.remove(ctx, index), nil
+}
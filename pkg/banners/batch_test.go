@@ -0,0 +1,135 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// batchState is what batch may change: banners, trash and count of versions
+type batchState struct {
+	items    []*Banner
+	trash    []int64
+	versions int
+}
+
+func stateOf(svc *Service) batchState {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+	state := batchState{items: append([]*Banner(nil), svc.items...)}
+	for id := range svc.trash {
+		state.trash = append(state.trash, id)
+	}
+	for _, versions := range svc.history {
+		state.versions += len(versions)
+	}
+	return state
+}
+
+func TestBatch(t *testing.T) {
+	tests := []struct {
+		name string
+		// ops change banners first and second saved before batch
+		ops    func(first, second *Banner) []BatchOp
+		atomic bool
+		err    error
+		// errs are errors of operations, applied is whether batch changes banners
+		errs    []error
+		applied bool
+	}{
+		{"atomic", func(first, second *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionCreate, Banner: &Banner{Title: "New", Content: "Text"}},
+				{Action: ActionUpdate, Banner: &Banner{ID: first.ID, Title: "Changed", Content: "Text", Revision: first.Revision}},
+				{Action: ActionDelete, ID: second.ID},
+			}
+		}, true, nil, []error{nil, nil, nil}, true},
+		{"atomic with stale revision", func(first, second *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionCreate, Banner: &Banner{Title: "New", Content: "Text"}},
+				{Action: ActionDelete, ID: second.ID},
+				{Action: ActionUpdate, Banner: &Banner{ID: first.ID, Title: "Changed", Content: "Text", Revision: first.Revision - 1}},
+			}
+		}, true, ErrBatchFailed, []error{nil, nil, ErrConflict}, false},
+		{"atomic with missing banner", func(first, _ *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionUpdate, Banner: &Banner{ID: first.ID, Title: "Changed", Content: "Text", Revision: first.Revision}},
+				{Action: ActionDelete, ID: first.ID + 100},
+			}
+		}, true, ErrBatchFailed, []error{nil, ErrNotFound}, false},
+		{"atomic with invalid banner", func(first, _ *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionDelete, ID: first.ID},
+				{Action: ActionCreate, Banner: &Banner{Content: "Text"}},
+			}
+		}, true, ErrBatchFailed, []error{nil, &ValidationError{}}, false},
+		{"atomic with duplicate", func(first, _ *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionUpdate, Banner: &Banner{ID: first.ID, Title: "Changed", Content: "Text", Revision: first.Revision}},
+				{Action: ActionDelete, ID: first.ID},
+			}
+		}, true, ErrBatchFailed, []error{nil, ErrBatchDuplicate}, false},
+		{"atomic with unknown action", func(first, _ *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionDelete, ID: first.ID},
+				{Action: ActionRestore, ID: first.ID},
+			}
+		}, true, ErrBatchFailed, []error{nil, ErrBatchAction}, false},
+		{"not atomic with stale revision", func(first, second *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionUpdate, Banner: &Banner{ID: first.ID, Title: "Changed", Content: "Text", Revision: first.Revision - 1}},
+				{Action: ActionDelete, ID: second.ID},
+			}
+		}, false, nil, []error{ErrConflict, nil}, true},
+		{"not atomic with duplicate", func(first, _ *Banner) []BatchOp {
+			return []BatchOp{
+				{Action: ActionDelete, ID: first.ID},
+				{Action: ActionDelete, ID: first.ID},
+			}
+		}, false, nil, []error{nil, ErrNotFound}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := NewService(storage.NewMemoryStore(""), 0)
+			ctx := context.Background()
+			first, err := svc.Save(ctx, &Banner{Title: "First", Content: "Text"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := svc.Save(ctx, &Banner{Title: "Second", Content: "Text"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := stateOf(svc)
+
+			results, err := svc.Batch(ctx, test.ops(first, second), test.atomic)
+			if err != test.err {
+				t.Fatalf("Batch() = %v, want %v", err, test.err)
+			}
+			for i, result := range results {
+				want := test.errs[i]
+				var verr *ValidationError
+				if errors.As(want, &verr) {
+					if !errors.As(result.Err, &verr) {
+						t.Errorf("operation %d error = %v, want validation error", i, result.Err)
+					}
+					continue
+				}
+				if result.Err != want {
+					t.Errorf("operation %d error = %v, want %v", i, result.Err, want)
+				}
+				if test.err == nil && (result.Err == nil) != (result.Banner != nil) {
+					t.Errorf("operation %d = %+v, %v", i, result.Banner, result.Err)
+				}
+			}
+
+			if applied := !reflect.DeepEqual(stateOf(svc), before); applied != test.applied {
+				t.Errorf("banners changed = %v, want %v", applied, test.applied)
+			}
+		})
+	}
+}
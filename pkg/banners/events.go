@@ -0,0 +1,189 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// EventLogSize is number of latest events kept for subscribers resuming from sequence
+const EventLogSize = 1024
+
+// MaxPendingEvents limits events queued for one subscriber, the one being sent included;
+// slow subscriber exceeding it is dropped: its channel is closed right away and events
+// queued for it are discarded, so it may resume from sequence of last received event
+const MaxPendingEvents = 4 * EventLogSize
+
+// ErrEventsExpired is returned by SubscribeFrom when events after sequence are not kept anymore
+// or sequence is unknown, e.g. it was received before restart
+var ErrEventsExpired = errors.New("events after sequence are not kept anymore")
+
+// EventType is kind of change of live banners
+type EventType string
+
+// Event types, restored banner is created again and rolled back one is updated
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// Event is change of banner; Before is nil for created banner, After of deleted one is
+// its soft deleted snapshot. Snapshots are copies, they are never changed.
+type Event struct {
+	// Sequence grows by one with every event of service, it starts from 1
+	Sequence uint64
+	Type     EventType
+	BannerID int64
+	Actor    string
+	At       time.Time
+	Before   *Banner
+	After    *Banner
+}
+
+// eventBus delivers events to subscribers, each of them has own queue and goroutine
+type eventBus struct {
+	mu          sync.Mutex
+	sequence    uint64
+	log         []Event
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	out  chan Event
+	wake chan struct{}
+	// done is closed when subscriber is dropped, delivery stops without waiting for it
+	done chan struct{}
+	// pending are events not received yet, first one is being sent
+	pending []Event
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Subscribe returns channel of events made after call, it is closed when ctx is done
+// or subscriber is dropped for being slow
+func (s *Service) Subscribe(ctx context.Context) <-chan Event {
+	events, _ := s.events.subscribe(ctx, 0, false)
+	return events
+}
+
+// SubscribeFrom is Subscribe starting right after event sequence, events made before call
+// are replayed first; it fails with ErrEventsExpired when some of them are not kept anymore
+func (s *Service) SubscribeFrom(ctx context.Context, sequence uint64) (<-chan Event, error) {
+	return s.events.subscribe(ctx, sequence, true)
+}
+
+// Sequence returns sequence of last event, 0 when there was none
+func (s *Service) Sequence() uint64 {
+	s.events.mu.Lock()
+	defer s.events.mu.Unlock()
+	return s.events.sequence
+}
+
+func (b *eventBus) subscribe(ctx context.Context, sequence uint64, replay bool) (<-chan Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscriber{out: make(chan Event), wake: make(chan struct{}, 1), done: make(chan struct{})}
+	if replay {
+		first := b.sequence - uint64(len(b.log)) + 1
+		if sequence+1 < first || sequence > b.sequence {
+			return nil, ErrEventsExpired
+		}
+		sub.pending = append(sub.pending, b.log[sequence+1-first:]...)
+	}
+	b.subscribers[sub] = struct{}{}
+	go b.deliver(ctx, sub)
+	return sub.out, nil
+}
+
+// deliver sends queued events one by one to subscriber until ctx is done or subscriber is dropped,
+// blocked send is abandoned in both cases; event is dequeued only after it is received
+func (b *eventBus) deliver(ctx context.Context, sub *subscriber) {
+	defer close(sub.out)
+	for {
+		b.mu.Lock()
+		var event Event
+		queued := len(sub.pending) != 0
+		if queued {
+			event = sub.pending[0]
+		}
+		b.mu.Unlock()
+
+		if queued {
+			select {
+			case sub.out <- event:
+				b.mu.Lock()
+				// queue of dropped subscriber is discarded already
+				if len(sub.pending) != 0 {
+					sub.pending[0] = Event{}
+					sub.pending = sub.pending[1:]
+				}
+				b.mu.Unlock()
+				continue
+			case <-sub.done:
+				return
+			case <-ctx.Done():
+				b.unsubscribe(sub)
+				return
+			}
+		}
+
+		select {
+		case <-sub.wake:
+		case <-sub.done:
+			return
+		case <-ctx.Done():
+			b.unsubscribe(sub)
+			return
+		}
+	}
+}
+
+func (b *eventBus) unsubscribe(sub *subscriber) {
+	b.mu.Lock()
+	delete(b.subscribers, sub)
+	b.mu.Unlock()
+}
+
+// publish numbers event, keeps it in log and queues it for subscribers without waiting for them
+func (b *eventBus) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	event.Sequence = b.sequence
+	if len(b.log) == EventLogSize {
+		copy(b.log, b.log[1:])
+		b.log = b.log[:len(b.log)-1]
+	}
+	b.log = append(b.log, event)
+
+	for sub := range b.subscribers {
+		if len(sub.pending) >= MaxPendingEvents {
+			sub.pending = nil
+			close(sub.done)
+			delete(b.subscribers, sub)
+			continue
+		}
+		sub.pending = append(sub.pending, event)
+		select {
+		case sub.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// eventType maps recorded action to event
+func eventType(action Action) EventType {
+	switch action {
+	case ActionCreate, ActionRestore:
+		return EventCreated
+	case ActionDelete:
+		return EventDeleted
+	}
+	return EventUpdated
+}
@@ -0,0 +1,231 @@
+package banners
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// receive reads n events or fails after timeout
+func receive(t *testing.T, events <-chan Event, n int) []uint64 {
+	t.Helper()
+	var sequences []uint64
+	for len(sequences) < n {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("channel closed after events %v, want %d", sequences, n)
+			}
+			sequences = append(sequences, event.Sequence)
+		case <-time.After(time.Second):
+			t.Fatalf("received events %v, want %d", sequences, n)
+		}
+	}
+	return sequences
+}
+
+func TestSubscribeFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		// published events are made before subscription, one more is made after it
+		published int
+		sequence  uint64
+		err       error
+		want      []uint64
+	}{
+		{"from start", 3, 0, nil, []uint64{1, 2, 3, 4}},
+		{"from middle", 3, 2, nil, []uint64{3, 4}},
+		{"from last", 3, 3, nil, []uint64{4}},
+		{"nothing published", 0, 0, nil, []uint64{1}},
+		{"unknown sequence", 3, 4, ErrEventsExpired, nil},
+		{"oldest kept", EventLogSize + 2, 2, nil, nil},
+		{"expired", EventLogSize + 2, 1, ErrEventsExpired, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bus := newEventBus()
+			for i := 0; i < test.published; i++ {
+				bus.publish(Event{Type: EventCreated, BannerID: int64(i + 1)})
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			events, err := bus.subscribe(ctx, test.sequence, true)
+			if err != test.err {
+				t.Fatalf("subscribe() = %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			bus.publish(Event{Type: EventDeleted, BannerID: 1})
+			want := test.want
+			if want == nil {
+				for sequence := test.sequence + 1; sequence <= uint64(test.published)+1; sequence++ {
+					want = append(want, sequence)
+				}
+			}
+			if got := receive(t, events, len(want)); !reflect.DeepEqual(got, want) {
+				t.Errorf("received %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSubscriberIsClosed(t *testing.T) {
+	tests := []struct {
+		name string
+		// stop ends subscription of subscriber which reads nothing
+		stop func(bus *eventBus, cancel context.CancelFunc)
+	}{
+		{"context done", func(_ *eventBus, cancel context.CancelFunc) {
+			cancel()
+		}},
+		{"dropped for being slow", func(bus *eventBus, _ context.CancelFunc) {
+			for i := 0; i < MaxPendingEvents+2; i++ {
+				bus.publish(Event{Type: EventUpdated, BannerID: 1})
+			}
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bus := newEventBus()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			events, err := bus.subscribe(ctx, 0, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// first event blocks delivery as nobody reads it
+			bus.publish(Event{Type: EventCreated, BannerID: 1})
+			time.Sleep(10 * time.Millisecond)
+
+			test.stop(bus, cancel)
+			deadline := time.After(time.Second)
+			received := 0
+			for open := true; open; {
+				select {
+				case _, open = <-events:
+					if open {
+						received++
+					}
+				case <-deadline:
+					t.Fatalf("channel is not closed, %d events received", received)
+				}
+			}
+			if received > 1 {
+				t.Errorf("%d events received after subscription ended, want at most blocked one", received)
+			}
+			bus.mu.Lock()
+			subscribers := len(bus.subscribers)
+			bus.mu.Unlock()
+			if subscribers != 0 {
+				t.Errorf("%d subscribers left", subscribers)
+			}
+		})
+	}
+}
+
+func TestServicePublishesEvents(t *testing.T) {
+	svc := NewService(storage.NewMemoryStore(""), 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := svc.Subscribe(ctx)
+
+	item, err := svc.Save(ctx, &Banner{Title: "Sale", Content: "Text"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := item.clone()
+	updated.Title = "Big sale"
+	if _, err = svc.Save(ctx, updated, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.RemoveByID(ctx, item.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.Restore(ctx, item.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typ    EventType
+		before string
+		after  string
+	}{
+		{EventCreated, "", "Sale"},
+		{EventUpdated, "Sale", "Big sale"},
+		{EventDeleted, "Big sale", "Big sale"},
+		{EventCreated, "Big sale", "Big sale"},
+	}
+	for i, test := range tests {
+		var event Event
+		select {
+		case event = <-events:
+		case <-time.After(time.Second):
+			t.Fatalf("event %d is not received", i+1)
+		}
+		title := func(item *Banner) string {
+			if item == nil {
+				return ""
+			}
+			return item.Title
+		}
+		if event.Sequence != uint64(i+1) || event.Type != test.typ || event.BannerID != item.ID ||
+			title(event.Before) != test.before || title(event.After) != test.after {
+			t.Errorf("event %d = %+v, want %s of %q to %q", i+1, event, test.typ, test.before, test.after)
+		}
+	}
+	if svc.Sequence() != uint64(len(tests)) {
+		t.Errorf("Sequence() = %d, want %d", svc.Sequence(), len(tests))
+	}
+}
+
+func TestPendingEventsIncludeSentOne(t *testing.T) {
+	bus := newEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := bus.subscribe(ctx, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// first event blocks delivery as nobody reads it, it still counts as pending
+	bus.publish(Event{Type: EventCreated, BannerID: 1})
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i < MaxPendingEvents; i++ {
+		bus.publish(Event{Type: EventUpdated, BannerID: 1})
+	}
+
+	bus.mu.Lock()
+	subscribers := len(bus.subscribers)
+	bus.mu.Unlock()
+	if subscribers != 1 {
+		t.Fatalf("subscriber with %d pending events is dropped", MaxPendingEvents)
+	}
+	bus.publish(Event{Type: EventUpdated, BannerID: 1})
+	bus.mu.Lock()
+	subscribers = len(bus.subscribers)
+	bus.mu.Unlock()
+	if subscribers != 0 {
+		t.Fatalf("subscriber with %d pending events is kept", MaxPendingEvents+1)
+	}
+
+	received := 0
+	for open := true; open; {
+		select {
+		case _, open = <-events:
+			if open {
+				received++
+			}
+		case <-time.After(time.Second):
+			t.Fatal("channel of dropped subscriber is not closed")
+		}
+	}
+	if received > 1 {
+		t.Errorf("%d events received after subscriber is dropped, want at most blocked one", received)
+	}
+}
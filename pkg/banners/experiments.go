@@ -0,0 +1,227 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrExperimentNotFound is returned when experiment by id does not exist
+var ErrExperimentNotFound = errors.New("experiment by id not found")
+
+// SignificanceLevel is p-value below which difference from control is significant
+const SignificanceLevel = 0.05
+
+// ExperimentVariant is banner taking part in experiment with its traffic share
+type ExperimentVariant struct {
+	BannerID int64
+	// Weight is relative share of traffic
+	Weight int
+}
+
+// Experiment groups banners as variants, first variant is control
+type Experiment struct {
+	ID        int64
+	Name      string
+	Running   bool
+	Variants  []ExperimentVariant
+	StartedAt time.Time
+}
+
+// VariantResult is outcome of one variant compared with control
+type VariantResult struct {
+	BannerID int64 `json:"id"`
+	Counters
+	CTR float64 `json:"ctr"`
+	// Lift is relative change of CTR against control
+	Lift   float64 `json:"lift"`
+	ZScore float64 `json:"z_score"`
+	PValue float64 `json:"p_value"`
+	// Significant reports whether PValue is below SignificanceLevel
+	Significant bool `json:"significant"`
+}
+
+// Experiments keeps A/B experiments over banners of service
+type Experiments struct {
+	mu         sync.RWMutex
+	items      []*Experiment
+	nextID     int64
+	bannersSvc *Service
+}
+
+// NewExperiments creates empty experiments storage
+func NewExperiments(bannersSvc *Service) *Experiments {
+	return &Experiments{items: make([]*Experiment, 0), bannersSvc: bannersSvc}
+}
+
+// All experiments
+func (e *Experiments) All(ctx context.Context) ([]*Experiment, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.items, nil
+}
+
+// ByID looks for experiment by id
+func (e *Experiments) ByID(ctx context.Context, id int64) (*Experiment, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, experiment := range e.items {
+		if experiment.ID == id {
+			return experiment, nil
+		}
+	}
+	return nil, ErrExperimentNotFound
+}
+
+// Save creates experiment when ID is 0 or replaces existing one,
+// StartedAt is set when experiment is started first time
+func (e *Experiments) Save(ctx context.Context, item *Experiment) (*Experiment, error) {
+	if err := e.validate(ctx, item); err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if item.ID == 0 {
+		e.nextID++
+		item.ID = e.nextID
+		if item.Running {
+			item.StartedAt = time.Now()
+		}
+		e.items = append(e.items, item)
+		return item, nil
+	}
+	for i, experiment := range e.items {
+		if experiment.ID == item.ID {
+			item.StartedAt = experiment.StartedAt
+			if item.Running && item.StartedAt.IsZero() {
+				item.StartedAt = time.Now()
+			}
+			e.items[i] = item
+			return item, nil
+		}
+	}
+	return nil, ErrExperimentNotFound
+}
+
+// RemoveByID removes experiment
+func (e *Experiments) RemoveByID(ctx context.Context, id int64) (*Experiment, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, experiment := range e.items {
+		if experiment.ID == id {
+			e.items = append(e.items[:i], e.items[i+1:]...)
+			return experiment, nil
+		}
+	}
+	return nil, ErrExperimentNotFound
+}
+
+func (e *Experiments) validate(ctx context.Context, item *Experiment) error {
+	verr := &ValidationError{}
+	checkLength(verr, "name", item.Name, MaxTitleLength, true)
+
+	if len(item.Variants) < 2 {
+		verr.add("variants", "at least two variants are required")
+	}
+	total := 0
+	seen := make(map[int64]bool)
+	for _, variant := range item.Variants {
+		if variant.Weight < 0 {
+			verr.add("variants", "weight must not be negative")
+		}
+		total += variant.Weight
+		if seen[variant.BannerID] {
+			verr.add("variants", "banner "+strconv.FormatInt(variant.BannerID, 10)+" is used twice")
+		}
+		seen[variant.BannerID] = true
+		if _, err := e.bannersSvc.ByID(ctx, variant.BannerID); err != nil {
+			verr.add("variants", "banner "+strconv.FormatInt(variant.BannerID, 10)+" not found")
+		}
+	}
+	if len(item.Variants) != 0 && total <= 0 {
+		verr.add("variants", "total weight must be positive")
+	}
+
+	if len(verr.Errors) != 0 {
+		return verr
+	}
+	return nil
+}
+
+// Assign returns variant for visitor, same visitor always gets same variant
+// while variants and weights of experiment are unchanged
+func (ex *Experiment) Assign(visitorID string) ExperimentVariant {
+	hash := fnv.New64a()
+	hash.Write([]byte(strconv.FormatInt(ex.ID, 10) + ":" + visitorID))
+
+	total := 0
+	for _, variant := range ex.Variants {
+		total += variant.Weight
+	}
+	n := int(hash.Sum64() % uint64(total))
+	for _, variant := range ex.Variants {
+		n -= variant.Weight
+		if n < 0 {
+			return variant
+		}
+	}
+	return ex.Variants[len(ex.Variants)-1]
+}
+
+// Results compares CTR of every variant with control (first variant)
+// using two-proportion z-test over counters recorded since experiment start
+func (ex *Experiment) Results(ctx context.Context, tracker *Tracker, now time.Time) []VariantResult {
+	from := ex.StartedAt
+	if from.IsZero() {
+		from = now
+	}
+
+	results := make([]VariantResult, len(ex.Variants))
+	for i, variant := range ex.Variants {
+		counters := tracker.Counters(ctx, variant.BannerID, from, now.Add(time.Hour))
+		results[i] = VariantResult{BannerID: variant.BannerID, Counters: counters, CTR: counters.CTR(), PValue: 1}
+	}
+	if len(results) == 0 {
+		return results
+	}
+
+	control := results[0]
+	for i := 1; i < len(results); i++ {
+		result := &results[i]
+		if control.CTR != 0 {
+			result.Lift = (result.CTR - control.CTR) / control.CTR
+		}
+		result.ZScore, result.PValue = zTest(control.Counters, result.Counters)
+		result.Significant = result.PValue < SignificanceLevel
+	}
+	return results
+}
+
+// zTest is two-sided two-proportion z-test of clicks/impressions; clicks are recorded apart
+// from impressions, so counters with more clicks than impressions are insufficient data too
+func zTest(a Counters, b Counters) (float64, float64) {
+	if !a.consistent() || !b.consistent() {
+		return 0, 1
+	}
+
+	n1, n2 := float64(a.Impressions), float64(b.Impressions)
+	p1, p2 := float64(a.Clicks)/n1, float64(b.Clicks)/n2
+	pooled := float64(a.Clicks+b.Clicks) / (n1 + n2)
+	se := math.Sqrt(pooled * (1 - pooled) * (1/n1 + 1/n2))
+	if se == 0 {
+		return 0, 1
+	}
+
+	z := (p2 - p1) / se
+	return z, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// consistent reports whether counters have impressions and at most as many clicks
+func (c Counters) consistent() bool {
+	return c.Impressions > 0 && c.Clicks >= 0 && c.Clicks <= c.Impressions
+}
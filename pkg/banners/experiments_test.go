@@ -0,0 +1,87 @@
+package banners
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestZTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		control Counters
+		variant Counters
+		z, p    float64
+	}{
+		{"better variant", Counters{Impressions: 1000, Clicks: 100}, Counters{Impressions: 1000, Clicks: 130}, 2.1027, 0.0355},
+		{"worse variant", Counters{Impressions: 500, Clicks: 60}, Counters{Impressions: 500, Clicks: 40}, -2.1082, 0.0350},
+		{"unequal samples", Counters{Impressions: 2000, Clicks: 50}, Counters{Impressions: 1000, Clicks: 40}, 2.2704, 0.0232},
+		{"same rate", Counters{Impressions: 1000, Clicks: 100}, Counters{Impressions: 1000, Clicks: 100}, 0, 1},
+		{"no impressions", Counters{}, Counters{Impressions: 1000, Clicks: 100}, 0, 1},
+		{"no clicks", Counters{Impressions: 1000}, Counters{Impressions: 1000}, 0, 1},
+		{"all clicked", Counters{Impressions: 10, Clicks: 10}, Counters{Impressions: 10, Clicks: 10}, 0, 1},
+		{"more clicks than impressions", Counters{Impressions: 10, Clicks: 30}, Counters{Impressions: 10, Clicks: 2}, 0, 1},
+		{"clicks without impressions", Counters{Impressions: 1000, Clicks: 100}, Counters{Clicks: 5}, 0, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			z, p := zTest(test.control, test.variant)
+			if math.IsNaN(z) || math.IsNaN(p) || math.Abs(z-test.z) > 1e-4 || math.Abs(p-test.p) > 1e-4 {
+				t.Errorf("zTest() = %.4f, %.4f, want %.4f, %.4f", z, p, test.z, test.p)
+			}
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []ExperimentVariant
+	}{
+		{"even split", []ExperimentVariant{{BannerID: 1, Weight: 50}, {BannerID: 2, Weight: 50}}},
+		{"uneven split", []ExperimentVariant{{BannerID: 1, Weight: 10}, {BannerID: 2, Weight: 30}, {BannerID: 3, Weight: 60}}},
+		{"single variant", []ExperimentVariant{{BannerID: 1, Weight: 1}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			experiment := &Experiment{ID: 7, Variants: test.variants}
+			total := 0
+			for _, variant := range test.variants {
+				total += variant.Weight
+			}
+
+			const visitors = 10000
+			assigned := make(map[int64]int)
+			for i := 0; i < visitors; i++ {
+				visitor := "visitor-" + strconv.Itoa(i)
+				variant := experiment.Assign(visitor)
+				if again := experiment.Assign(visitor); again != variant {
+					t.Fatalf("Assign(%q) = %d, then %d", visitor, variant.BannerID, again.BannerID)
+				}
+				assigned[variant.BannerID]++
+			}
+			for _, variant := range test.variants {
+				share := float64(assigned[variant.BannerID]) / visitors
+				want := float64(variant.Weight) / float64(total)
+				if math.Abs(share-want) > 0.03 {
+					t.Errorf("variant %d gets %.3f of visitors, want %.3f", variant.BannerID, share, want)
+				}
+			}
+
+			// another experiment splits same visitors independently
+			other := &Experiment{ID: 8, Variants: test.variants}
+			same := 0
+			for i := 0; i < visitors; i++ {
+				visitor := "visitor-" + strconv.Itoa(i)
+				if other.Assign(visitor) == experiment.Assign(visitor) {
+					same++
+				}
+			}
+			if len(test.variants) > 1 && same == visitors {
+				t.Errorf("experiments %d and %d assign every visitor alike", experiment.ID, other.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+)
+
+// ErrNotFound is returned when banner by id does not exist (or is deleted)
+var ErrNotFound = errors.New("banner by id not found")
+
+// ErrVersionNotFound is returned when banner has no such version
+var ErrVersionNotFound = errors.New("banner version not found")
+
+// Action is kind of change recorded in history
+type Action string
+
+// Recorded actions
+const (
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionDelete   Action = "delete"
+	ActionRestore  Action = "restore"
+	ActionRollback Action = "rollback"
+)
+
+// FieldChange is value of one field before and after change
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Version is one entry of banner history, Snapshot is banner right after change
+type Version struct {
+	Version  int
+	Action   Action
+	Actor    string
+	At       time.Time
+	Changes  []FieldChange
+	Snapshot Banner
+}
+
+type actorKey struct{}
+
+// WithActor returns context carrying name of whoever makes changes
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns actor stored by WithActor, "anonymous" when there is none
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "anonymous"
+}
+
+// History returns all versions of banner, oldest first, deleted banners included
+func (s *Service) History(ctx context.Context, id int64) ([]*Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions, ok := s.history[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]*Version(nil), versions...), nil
+}
+
+// Restore brings soft deleted banner back
+func (s *Service) Restore(ctx context.Context, id int64) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "restore")
+	defer func() { end(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	banner, ok := s.trash[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	restored := banner.clone()
+	restored.DeletedAt = time.Time{}
+	delete(s.trash, id)
+	s.insert(restored)
+	s.record(ctx, ActionRestore, banner, restored)
+	return restored, nil
+}
+
+// Rollback makes banner of given revision look like in given version, it fails with ErrConflict
+// when banner was changed since revision; image is not rolled back because replaced images are removed from store
+func (s *Service) Rollback(ctx context.Context, id int64, revision int64, version int) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "rollback")
+	defer func() { end(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.indexOf(id)
+	if index == -1 {
+		return nil, ErrNotFound
+	}
+	current := s.items[index]
+	if current.Revision != revision {
+		return nil, ErrConflict
+	}
+
+	var target *Version
+	for _, v := range s.history[id] {
+		if v.Version == version {
+			target = v
+			break
+		}
+	}
+	if target == nil {
+		return nil, ErrVersionNotFound
+	}
+
+	// snapshot of deleted version is soft deleted and carries old revision
+	item := target.Snapshot.clone()
+	item.DeletedAt = time.Time{}
+	item.Revision = current.Revision
+	item.Image = current.Image
+	item.Width = current.Width
+	item.Height = current.Height
+	item.Variants = current.Variants
+	if err := Validate(item); err != nil {
+		return nil, err
+	}
+
+	s.items[index] = item
+	s.record(ctx, ActionRollback, current, item)
+	return item, nil
+}
+
+// Purge removes soft deleted banner permanently together with its images and history
+func (s *Service) Purge(ctx context.Context, id int64) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "purge")
+	defer func() { end(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	banner, ok := s.trash[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	delete(s.trash, id)
+	delete(s.history, id)
+	s.removeImages(ctx, imageKeys(banner), nil)
+	return banner, nil
+}
+
+// record appends version to history, sets revision of after to number of version,
+// updates search index and publishes event, before is nil for created banner; must be called under lock
+func (s *Service) record(ctx context.Context, action Action, before *Banner, after *Banner) {
+	versions := s.history[after.ID]
+	after.Revision = int64(len(versions) + 1)
+	actor, now := ActorFromContext(ctx), time.Now()
+	s.history[after.ID] = append(versions, &Version{
+		Version:  len(versions) + 1,
+		Action:   action,
+		Actor:    actor,
+		At:       now,
+		Changes:  diff(before, after),
+		Snapshot: *after.clone(),
+	})
+	if action == ActionDelete {
+		s.search.remove(after.ID)
+	} else {
+		s.search.add(after)
+	}
+
+	event := Event{Type: eventType(action), BannerID: after.ID, Actor: actor, At: now, After: after.clone()}
+	if before != nil {
+		event.Before = before.clone()
+	}
+	s.events.publish(event)
+	logging.FromContext(ctx).Info("banner changed",
+		"action", action, "banner_id", after.ID, "revision", after.Revision)
+}
+
+// insert puts item in position ordered by id, so items stay ordered by id
+// whatever order ids are reserved in; must be called under lock
+func (s *Service) insert(item *Banner) {
+	i := len(s.items)
+	for i > 0 && s.items[i-1].ID > item.ID {
+		i--
+	}
+	s.items = append(s.items, nil)
+	copy(s.items[i+1:], s.items[i:])
+	s.items[i] = item
+}
+
+func (s *Service) indexOf(id int64) int {
+	for i, banner := range s.items {
+		if banner.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// historyFields are public fields of Banner compared by diff; Image stands for the whole image
+// (its size and variants) because replaced image is stored under new key, while ID, Revision
+// and DeletedAt change on their own and are not worth diffing
+var historyFields = []string{
+	"Title", "Content", "Button", "Link", "Image", "Status",
+	"StartsAt", "EndsAt", "Priority", "Targeting", "Weight",
+}
+
+// diff lists every field of historyFields which differs between before and after
+func diff(before *Banner, after *Banner) []FieldChange {
+	if before == nil {
+		before = &Banner{}
+	}
+
+	changes := make([]FieldChange, 0)
+	beforeValue, afterValue := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	for _, name := range historyFields {
+		old, current := beforeValue.FieldByName(name).Interface(), afterValue.FieldByName(name).Interface()
+		if !reflect.DeepEqual(old, current) {
+			changes = append(changes, FieldChange{Field: name, Before: old, After: current})
+		}
+	}
+	return changes
+}
+
+// clone returns deep copy of banner, so history snapshots are not changed later
+func (b *Banner) clone() *Banner {
+	copied := *b
+	if b.Variants != nil {
+		copied.Variants = make(map[int]string, len(b.Variants))
+		for width, key := range b.Variants {
+			copied.Variants[width] = key
+		}
+	}
+	copied.Targeting.Locales = append([]string(nil), b.Targeting.Locales...)
+	copied.Targeting.Devices = append([]Device(nil), b.Targeting.Devices...)
+	if b.Targeting.Segments != nil {
+		copied.Targeting.Segments = make(map[string]string, len(b.Targeting.Segments))
+		for key, value := range b.Targeting.Segments {
+			copied.Targeting.Segments[key] = value
+		}
+	}
+	return &copied
+}
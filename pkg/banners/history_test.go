@@ -0,0 +1,139 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	// history of banner: 1 create, 2 update, 3 delete, 4 restore, 5 update
+	prepare := func(t *testing.T) (*Service, *Banner) {
+		t.Helper()
+		svc := NewService(storage.NewMemoryStore(""), 1<<20)
+		item, err := svc.Save(ctx, &Banner{Title: "First", Content: "Half price"}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		item = item.clone()
+		item.Title = "Second"
+		if _, err = svc.Save(ctx, item, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = svc.RemoveByID(ctx, item.ID); err != nil {
+			t.Fatal(err)
+		}
+		if item, err = svc.Restore(ctx, item.ID); err != nil {
+			t.Fatal(err)
+		}
+		item = item.clone()
+		item.Title = "Third"
+		if item, err = svc.Save(ctx, item, nil); err != nil {
+			t.Fatal(err)
+		}
+		return svc, item
+	}
+
+	tests := []struct {
+		name string
+		id   int64
+		// revision is current one when 0
+		revision int64
+		version  int
+		title    string
+		err      error
+	}{
+		{"created version", 0, 0, 1, "First", nil},
+		{"deleted version", 0, 0, 3, "Second", nil},
+		{"current version", 0, 0, 5, "Third", nil},
+		{"unknown version", 0, 0, 9, "", ErrVersionNotFound},
+		{"unknown banner", 999, 0, 1, "", ErrNotFound},
+		{"stale revision", 0, 1, 1, "", ErrConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc, current := prepare(t)
+			id := test.id
+			if id == 0 {
+				id = current.ID
+			}
+
+			revision := test.revision
+			if revision == 0 {
+				revision = current.Revision
+			}
+
+			item, err := svc.Rollback(ctx, id, revision, test.version)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Rollback() = %v, want %v", err, test.err)
+			}
+			if err != nil {
+				return
+			}
+			if item.Title != test.title {
+				t.Errorf("title = %q, want %q", item.Title, test.title)
+			}
+			if !item.DeletedAt.IsZero() {
+				t.Errorf("rolled back banner is deleted at %v", item.DeletedAt)
+			}
+			if item.Revision != current.Revision+1 {
+				t.Errorf("revision = %d, want %d", item.Revision, current.Revision+1)
+			}
+
+			stored, err := svc.ByID(ctx, id)
+			if err != nil || stored != item {
+				t.Fatalf("ByID() = %v, %v, want rolled back banner", stored, err)
+			}
+			// rolled back banner is saved with its revision like any other one
+			next := item.clone()
+			next.Title = "Fourth"
+			if _, err := svc.Save(ctx, next, nil); err != nil {
+				t.Errorf("Save() after rollback = %v", err)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	base := &Banner{ID: 1, Title: "Sale", Content: "Half price", Image: "1-ab.png", Width: 700, Height: 10, Revision: 3}
+	changed := func(change func(item *Banner)) *Banner {
+		item := base.clone()
+		change(item)
+		return item
+	}
+
+	tests := []struct {
+		name   string
+		before *Banner
+		after  *Banner
+		fields []string
+	}{
+		{"created", nil, base, []string{"Title", "Content", "Image"}},
+		{"title", base, changed(func(item *Banner) { item.Title = "Big sale" }), []string{"Title"}},
+		{"image replaced", base, changed(func(item *Banner) {
+			item.Image, item.Width, item.Variants = "1-cd.png", 1400, map[int]string{640: "1-cd-640.png"}
+		}), []string{"Image"}},
+		{"internal fields only", base, changed(func(item *Banner) {
+			item.Revision, item.DeletedAt, item.Variants = 4, time.Now(), map[int]string{320: "x.png"}
+		}), nil},
+		{"targeting", base, changed(func(item *Banner) { item.Targeting.Devices = []Device{DeviceMobile} }), []string{"Targeting"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var fields []string
+			for _, change := range diff(test.before, test.after) {
+				fields = append(fields, change.Field)
+			}
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("diff() fields = %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package banners
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"image"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	// decoders for allowed image types
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// MaxImageSize is default limit of uploaded image (in bytes)
+const MaxImageSize int64 = 5 << 20
+
+// MaxImagePixels limits width×height of uploaded image, it is checked before decoding
+// so small file declaring huge size is rejected without allocating its pixels
+const MaxImagePixels = 8192 * 8192
+
+// ImageTypes maps allowed mime types to stored file extensions
+var ImageTypes = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+}
+
+type uploadedImage struct {
+	img    image.Image
+	data   []byte
+	mime   string
+	ext    string
+	width  int
+	height int
+}
+
+// readImage reads at most maxSize bytes, checks real type of content and decodes it
+func readImage(file io.Reader, maxSize int64) (*uploadedImage, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, errors.New("error read file")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, imageError("must be at most " + strconv.FormatInt(maxSize, 10) + " bytes")
+	}
+
+	mimeType := http.DetectContentType(data)
+	ext, ok := ImageTypes[mimeType]
+	if !ok {
+		return nil, imageError("type " + strconv.Quote(mimeType) + " is not allowed")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, imageError("is not a valid image")
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > MaxImagePixels {
+		return nil, imageError("must have at most " + strconv.Itoa(MaxImagePixels) + " pixels")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, imageError("is not a valid image")
+	}
+	bounds := img.Bounds()
+
+	return &uploadedImage{img: img, data: data, mime: mimeType, ext: ext, width: bounds.Dx(), height: bounds.Dy()}, nil
+}
+
+func imageError(message string) error {
+	return &ValidationError{Errors: []FieldError{{Field: "image", Message: message}}}
+}
+
+// storedImage is image with variants written to store, banner references it after apply
+type storedImage struct {
+	key      string
+	width    int
+	height   int
+	variants map[int]string
+}
+
+// apply makes banner reference image, nil image changes nothing
+func (img *storedImage) apply(banner *Banner) {
+	if img == nil {
+		return
+	}
+	banner.Image = img.key
+	banner.Width = img.width
+	banner.Height = img.height
+	banner.Variants = img.variants
+}
+
+// keys returns keys of image and its variants
+func (img *storedImage) keys() []string {
+	keys := []string{img.key}
+	for _, key := range img.variants {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// uploadToken makes keys of every upload unique
+func uploadToken() string {
+	var token [4]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(token[:])
+}
@@ -0,0 +1,76 @@
+package banners
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// declareSize rewrites size in IHDR chunk of PNG keeping its checksum valid
+func declareSize(data []byte, width, height uint32) []byte {
+	data = append([]byte(nil), data...)
+	// signature (8), chunk length (4) and type (4) precede IHDR data
+	ihdr := data[16:29]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestReadImage(t *testing.T) {
+	small := pngImage(t, 3, 2)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		maxSize int64
+		message string
+	}{
+		{"png", small, 1 << 20, ""},
+		{"too many bytes", small, 10, "must be at most 10 bytes"},
+		{"not an image", []byte("<html>hello</html>"), 1 << 20, "is not allowed"},
+		{"broken png", small[:40], 1 << 20, "is not a valid image"},
+		{"decompression bomb", declareSize(small, 50000, 50000), 1 << 20, "pixels"},
+		{"zero width", declareSize(small, 0, 2), 1 << 20, "is not a valid image"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			upload, err := readImage(bytes.NewReader(test.data), test.maxSize)
+			if test.message == "" {
+				if err != nil {
+					t.Fatalf("readImage() = %v", err)
+				}
+				if upload.width != 3 || upload.height != 2 || upload.ext != "png" {
+					t.Errorf("readImage() = %dx%d %s, want 3x2 png", upload.width, upload.height, upload.ext)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) || len(verr.Errors) != 1 || verr.Errors[0].Field != "image" {
+				t.Fatalf("readImage() = %v, want image field error", err)
+			}
+			if !strings.Contains(verr.Errors[0].Message, test.message) {
+				t.Errorf("message = %q, want it to contain %q", verr.Errors[0].Message, test.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package banners
+
+import (
+	"errors"
+
+	"github.com/MrHakimov/http/pkg/metrics"
+)
+
+// serviceMetrics counts operations of Service, nil one records nothing
+type serviceMetrics struct {
+	operations *metrics.Counter
+	imageBytes *metrics.Histogram
+}
+
+// SetMetrics makes service count its operations and uploaded image sizes in registry
+func (s *Service) SetMetrics(registry *metrics.Registry) {
+	s.metrics = &serviceMetrics{
+		operations: registry.NewCounter("banners_operations_total",
+			"Operations of banners service by result.", "operation", "result"),
+		imageBytes: registry.NewHistogram("banners_image_upload_bytes",
+			"Size of uploaded banner images.", metrics.SizeBuckets),
+	}
+}
+
+func (m *serviceMetrics) operation(name string, err error) {
+	if m == nil {
+		return
+	}
+	m.operations.Inc(name, resultOf(err))
+}
+
+func (m *serviceMetrics) imageUploaded(size int) {
+	if m == nil {
+		return
+	}
+	m.imageBytes.Observe(float64(size))
+}
+
+// resultOf names outcome of operation for metrics
+func resultOf(err error) string {
+	var verr *ValidationError
+	switch {
+	case err == nil:
+		return "ok"
+	case err == ErrNotFound || err == ErrVersionNotFound || err == ErrNoBanner:
+		return "not_found"
+	case err == ErrConflict:
+		return "conflict"
+	case errors.As(err, &verr) || err == ErrBatchFailed:
+		return "invalid"
+	}
+	return "error"
+}
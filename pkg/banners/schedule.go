@@ -0,0 +1,59 @@
+package banners
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Status of banner in publishing workflow
+type Status string
+
+// Supported statuses, only published banners are ever shown
+const (
+	StatusDraft     Status = "draft"
+	StatusPublished Status = "published"
+	StatusArchived  Status = "archived"
+)
+
+// Valid reports whether status is one of supported
+func (s Status) Valid() bool {
+	switch s {
+	case StatusDraft, StatusPublished, StatusArchived:
+		return true
+	}
+	return false
+}
+
+// IsActive reports whether banner is published and now is inside its window,
+// zero StartsAt or EndsAt means window is not limited from that side
+func (b *Banner) IsActive(now time.Time) bool {
+	if b.Status != StatusPublished {
+		return false
+	}
+	if !b.StartsAt.IsZero() && now.Before(b.StartsAt) {
+		return false
+	}
+	if !b.EndsAt.IsZero() && !now.Before(b.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// Active returns banners eligible at now, higher priority first
+func (s *Service) Active(ctx context.Context, now time.Time) ([]*Banner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	active := make([]*Banner, 0)
+	for _, banner := range s.items {
+		if banner.IsActive(now) {
+			active = append(active, banner)
+		}
+	}
+
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].Priority > active[j].Priority
+	})
+	return active, nil
+}
@@ -0,0 +1,93 @@
+package banners
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+func TestIsActive(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		status Status
+		starts time.Time
+		ends   time.Time
+		want   bool
+	}{
+		{"published without window", StatusPublished, time.Time{}, time.Time{}, true},
+		{"draft", StatusDraft, time.Time{}, time.Time{}, false},
+		{"archived", StatusArchived, time.Time{}, time.Time{}, false},
+		{"inside window", StatusPublished, now.Add(-time.Hour), now.Add(time.Hour), true},
+		{"not started", StatusPublished, now.Add(time.Second), time.Time{}, false},
+		{"starts now", StatusPublished, now, time.Time{}, true},
+		{"ended", StatusPublished, time.Time{}, now.Add(-time.Second), false},
+		{"ends now", StatusPublished, time.Time{}, now, false},
+		{"draft inside window", StatusDraft, now.Add(-time.Hour), now.Add(time.Hour), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			banner := &Banner{Status: test.status, StartsAt: test.starts, EndsAt: test.ends}
+			if got := banner.IsActive(now); got != test.want {
+				t.Errorf("IsActive() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestActive(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewService(storage.NewMemoryStore(""), 0)
+	ctx := context.Background()
+	for _, item := range []*Banner{
+		{Title: "Low", Status: StatusPublished, Priority: 1},
+		{Title: "Draft", Status: StatusDraft, Priority: 9},
+		{Title: "High", Status: StatusPublished, Priority: 5},
+		{Title: "Expired", Status: StatusPublished, Priority: 9, EndsAt: now.Add(-time.Minute)},
+		{Title: "Also low", Status: StatusPublished, Priority: 1, StartsAt: now.Add(-time.Minute)},
+		{Title: "Future", Status: StatusPublished, Priority: 9, StartsAt: now.Add(time.Minute)},
+		{Title: "Removed", Status: StatusPublished, Priority: 9},
+	} {
+		item.Content = "Text"
+		saved, err := svc.Save(ctx, item, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved.Title == "Removed" {
+			if _, err = svc.RemoveByID(ctx, saved.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{"now", now, []string{"High", "Low", "Also low"}},
+		{"after future one starts", now.Add(time.Hour), []string{"Future", "High", "Low", "Also low"}},
+		{"before expired one ends", now.Add(-time.Hour), []string{"Expired", "High", "Low"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			active, err := svc.Active(ctx, test.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for _, item := range active {
+				titles = append(titles, item.Title)
+			}
+			if !reflect.DeepEqual(titles, test.want) {
+				t.Errorf("Active() = %v, want %v", titles, test.want)
+			}
+		})
+	}
+}
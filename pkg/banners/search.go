@@ -0,0 +1,288 @@
+package banners
+
+import (
+	"context"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Searched fields and weights of their words in ranking
+var searchFields = []struct {
+	name   string
+	weight float64
+	text   func(item *Banner) string
+}{
+	{"title", 3, func(item *Banner) string { return item.Title }},
+	{"button", 2, func(item *Banner) string { return item.Button }},
+	{"content", 1, func(item *Banner) string { return item.Content }},
+}
+
+// prefixMatchWeight scales score of word matched only by prefix of query word
+const prefixMatchWeight = 0.5
+
+// snippetLength is number of characters of longest snippet, ellipses not included
+const snippetLength = 160
+
+// SearchHit is banner found by Search
+type SearchHit struct {
+	Banner *Banner
+	Score  float64
+	// Highlights are snippets of matched fields by name (title, content, button), matched words
+	// are wrapped into <mark> and </mark>, the rest of text is HTML escaped
+	Highlights map[string]string
+}
+
+// searchIndex is inverted index of words of live banners; guarded by Service.mu
+type searchIndex struct {
+	// postings map word to weighted number of its occurrences in banner by id
+	postings map[string]map[int64]float64
+	// words are indexed words, sorted for prefix lookup
+	words []string
+	// docs are words of banner by id, they are removed from postings when banner changes
+	docs map[int64][]string
+	// banners are indexed banners by id
+	banners map[int64]*Banner
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int64]float64),
+		docs:     make(map[int64][]string),
+		banners:  make(map[int64]*Banner),
+	}
+}
+
+// add indexes item replacing its previous words
+func (x *searchIndex) add(item *Banner) {
+	x.remove(item.ID)
+	weights := make(map[string]float64)
+	for _, field := range searchFields {
+		for _, token := range tokenize(field.text(item)) {
+			weights[token.word] += field.weight
+		}
+	}
+
+	words := make([]string, 0, len(weights))
+	for word, weight := range weights {
+		postings, ok := x.postings[word]
+		if !ok {
+			postings = make(map[int64]float64)
+			x.postings[word] = postings
+			i := sort.SearchStrings(x.words, word)
+			x.words = append(x.words, "")
+			copy(x.words[i+1:], x.words[i:])
+			x.words[i] = word
+		}
+		postings[item.ID] = weight
+		words = append(words, word)
+	}
+	x.docs[item.ID] = words
+	x.banners[item.ID] = item
+}
+
+// remove drops banner by id from index
+func (x *searchIndex) remove(id int64) {
+	for _, word := range x.docs[id] {
+		postings := x.postings[word]
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(x.postings, word)
+			i := sort.SearchStrings(x.words, word)
+			x.words = append(x.words[:i], x.words[i+1:]...)
+		}
+	}
+	delete(x.docs, id)
+	delete(x.banners, id)
+}
+
+// match scores banners containing word starting with each of terms, score of term is weight
+// of matched word scaled by its rarity, words longer than term count less
+func (x *searchIndex) match(terms []string) map[int64]float64 {
+	var scores map[int64]float64
+	for _, term := range terms {
+		termScores := make(map[int64]float64)
+		for i := sort.SearchStrings(x.words, term); i < len(x.words) && strings.HasPrefix(x.words[i], term); i++ {
+			word := x.words[i]
+			postings := x.postings[word]
+			idf := math.Log(1 + float64(len(x.docs))/float64(len(postings)))
+			if word != term {
+				idf *= prefixMatchWeight
+			}
+			for id, weight := range postings {
+				if scores == nil || scores[id] != 0 {
+					termScores[id] += weight * idf
+				}
+			}
+		}
+		for id, score := range termScores {
+			termScores[id] = score + scores[id]
+		}
+		scores = termScores
+		if len(scores) == 0 {
+			break
+		}
+	}
+	return scores
+}
+
+// Search finds live banners having every word of query as word or prefix of word
+// in title, content or button, best matching go first; limit is ignored when not positive
+func (s *Service) Search(ctx context.Context, query string, limit int) (hits []SearchHit, err error) {
+	ctx, end := s.begin(ctx, "search")
+	defer func() { end(err) }()
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	scores := s.search.match(terms)
+	hits = make([]SearchHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, SearchHit{Banner: s.search.banners[id], Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Banner.ID < hits[j].Banner.ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	for i := range hits {
+		hits[i].Highlights = make(map[string]string)
+		for _, field := range searchFields {
+			if snippet, ok := highlight(field.text(hits[i].Banner), terms); ok {
+				hits[i].Highlights[field.name] = snippet
+			}
+		}
+	}
+	return hits, nil
+}
+
+// searchTerms returns distinct words of query
+func searchTerms(query string) []string {
+	var terms []string
+	for _, token := range tokenize(query) {
+		if !containsString(terms, token.word) {
+			terms = append(terms, token.word)
+		}
+	}
+	return terms
+}
+
+// token is case folded word of text, start and end are its byte offsets in text
+type token struct {
+	word       string
+	start, end int
+}
+
+// tokenize splits text into runs of letters and digits
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		wordRune := isWordRune(r)
+		switch {
+		case wordRune && start == -1:
+			start = i
+		case !wordRune && start != -1:
+			tokens = append(tokens, token{word: foldCase(text[start:i]), start: start, end: i})
+			start = -1
+		}
+	}
+	if start != -1 {
+		tokens = append(tokens, token{word: foldCase(text[start:]), start: start, end: len(text)})
+	}
+	return tokens
+}
+
+// foldCase maps word to form equal for all its case variants
+func foldCase(word string) string {
+	return strings.ToLower(strings.ToUpper(word))
+}
+
+// highlight marks words of text starting with one of terms, long text is cut around
+// first match; ok is false when nothing matches
+func highlight(text string, terms []string) (snippet string, ok bool) {
+	var matched []token
+	for _, token := range tokenize(text) {
+		for _, term := range terms {
+			if strings.HasPrefix(token.word, term) {
+				matched = append(matched, token)
+				break
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return "", false
+	}
+
+	from, to := 0, len(text)
+	if utf8.RuneCountInString(text) > snippetLength {
+		from = moveRunes(text, matched[0].start, -snippetLength/4)
+		to = moveRunes(text, from, snippetLength)
+		if to < matched[0].end {
+			to = matched[0].end
+		}
+		from, to = wordBoundary(text, from, -1), wordBoundary(text, to, 1)
+	}
+
+	var builder strings.Builder
+	if from > 0 {
+		builder.WriteString("…")
+	}
+	position := from
+	for _, token := range matched {
+		if token.start < from || token.end > to {
+			continue
+		}
+		builder.WriteString(html.EscapeString(text[position:token.start]))
+		builder.WriteString("<mark>")
+		builder.WriteString(html.EscapeString(text[token.start:token.end]))
+		builder.WriteString("</mark>")
+		position = token.end
+	}
+	builder.WriteString(html.EscapeString(text[position:to]))
+	if to < len(text) {
+		builder.WriteString("…")
+	}
+	return builder.String(), true
+}
+
+// moveRunes returns byte offset n runes after (or before when n is negative) offset
+func moveRunes(text string, offset int, n int) int {
+	for ; n < 0 && offset > 0; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:offset])
+		offset -= size
+	}
+	for ; n > 0 && offset < len(text); n-- {
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+// wordBoundary moves offset in direction (-1 or 1) out of word it splits
+func wordBoundary(text string, offset int, direction int) int {
+	for offset > 0 && offset < len(text) {
+		before, _ := utf8.DecodeLastRuneInString(text[:offset])
+		after, _ := utf8.DecodeRuneInString(text[offset:])
+		if !isWordRune(before) || !isWordRune(after) {
+			break
+		}
+		offset = moveRunes(text, offset, direction)
+	}
+	return offset
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
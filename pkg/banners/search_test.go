@@ -0,0 +1,161 @@
+package banners
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+func TestSearchRanking(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []*Banner
+		query string
+		// want are titles of hits in order
+		want []string
+	}{
+		{"title over button over content", []*Banner{
+			{Title: "In content", Content: "Summer sale"},
+			{Title: "Summer sale", Content: "In title"},
+			{Title: "In button", Content: "Text", Button: "Summer sale"},
+		}, "sale", []string{"Summer sale", "In button", "In content"}},
+		{"exact word over prefix", []*Banner{
+			{Title: "Salesman", Content: "Text"},
+			{Title: "Sale", Content: "Text"},
+		}, "sale", []string{"Sale", "Salesman"}},
+		{"every word must match", []*Banner{
+			{Title: "Summer sale", Content: "Text"},
+			{Title: "Winter sale", Content: "Text"},
+			{Title: "Summer camp", Content: "Text"},
+		}, "summer sale", []string{"Summer sale"}},
+		{"rare word over common one", []*Banner{
+			{Title: "New hats", Content: "Old"},
+			{Title: "Old hats", Content: "New"},
+			{Title: "New shoes", Content: "Text"},
+			{Title: "New boots", Content: "Text"},
+		}, "new old", []string{"Old hats", "New hats"}},
+		{"repeated word counts more", []*Banner{
+			{Title: "Sale", Content: "Text"},
+			{Title: "Sale again", Content: "Sale everywhere"},
+		}, "sale", []string{"Sale again", "Sale"}},
+		{"case of letters", []*Banner{
+			{Title: "STRASSE Café", Content: "Text"},
+		}, "café strasse", []string{"STRASSE Café"}},
+		{"no match", []*Banner{
+			{Title: "Sale", Content: "Text"},
+		}, "winter", nil},
+		{"punctuation only", []*Banner{
+			{Title: "Sale", Content: "Text"},
+		}, "!?", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := NewService(storage.NewMemoryStore(""), 0)
+			ctx := context.Background()
+			for _, item := range test.items {
+				if _, err := svc.Save(ctx, item, nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			hits, err := svc.Search(ctx, test.query, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			for i, hit := range hits {
+				titles = append(titles, hit.Banner.Title)
+				if i > 0 && hit.Score > hits[i-1].Score {
+					t.Errorf("hit %d scores %v over %v of previous one", i, hit.Score, hits[i-1].Score)
+				}
+			}
+			if !reflect.DeepEqual(titles, test.want) {
+				t.Errorf("Search(%q) = %q, want %q", test.query, titles, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndexFollowsChanges(t *testing.T) {
+	svc := NewService(storage.NewMemoryStore(""), 0)
+	ctx := context.Background()
+	kept, err := svc.Save(ctx, &Banner{Title: "Summer sale", Content: "Text"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := svc.Save(ctx, &Banner{Title: "Summer camp", Content: "Text"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := svc.Save(ctx, &Banner{Title: "Summer hats", Content: "Text"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed = changed.clone()
+	changed.Title = "Winter camp"
+	if _, err = svc.Save(ctx, changed, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = svc.RemoveByID(ctx, removed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"summer", []int64{kept.ID}},
+		{"winter", []int64{changed.ID}},
+		{"hats", nil},
+		{"camp", []int64{changed.ID}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			hits, err := svc.Search(ctx, test.query, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []int64
+			for _, hit := range hits {
+				ids = append(ids, hit.Banner.ID)
+			}
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("Search(%q) = %v, want %v", test.query, ids, test.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	long := strings.Repeat("word ", 60) + "sale " + strings.Repeat("word ", 60)
+
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  string
+		ok    bool
+	}{
+		{"word", "Summer sale", []string{"sale"}, "Summer <mark>sale</mark>", true},
+		{"prefix", "Sales today", []string{"sale"}, "<mark>Sales</mark> today", true},
+		{"several terms", "Big summer sale", []string{"sale", "big"}, "<mark>Big</mark> summer <mark>sale</mark>", true},
+		{"text is escaped", "<b>Sale</b> & more", []string{"sale"}, "&lt;b&gt;<mark>Sale</mark>&lt;/b&gt; &amp; more", true},
+		{"no match", "Summer sale", []string{"winter"}, "", false},
+		{"long text is cut around match", long, []string{"sale"},
+			"…" + strings.TrimSpace(strings.Repeat("word ", 8)) + " <mark>sale</mark> " + strings.Repeat("word ", 23) + "…", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := highlight(test.text, test.terms)
+			if got != test.want || ok != test.ok {
+				t.Errorf("highlight() = %q, %v, want %q, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
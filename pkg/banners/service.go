@@ -1,19 +1,29 @@
 package banners
 
 import (
+	"bytes"
 	"context"
 	"errors"
-	"fmt"
-	"io/ioutil"
+	"io"
+	"math/rand"
 	"mime/multipart"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/storage"
+	"github.com/MrHakimov/http/pkg/tracing"
 )
 
-// STORAGE is place where to store
+// STORAGE is default place where to store images
 const STORAGE = "./web/banners/"
 
 var starID int64 = 0
 
+// ErrConflict is returned by Save and Rollback when banner was changed since revision of item
+var ErrConflict = errors.New("banner revision conflict")
+
 // Banner type
 type Banner struct {
 	ID      int64
@@ -22,17 +32,73 @@ type Banner struct {
 	Button  string
 	Link    string
 	Image   string
+	Width   int
+	Height  int
+	// Variants maps width of resized copy to its key in store
+	Variants map[int]string
+	Status   Status
+	StartsAt time.Time
+	EndsAt   time.Time
+	// Priority orders active banners, bigger goes first
+	Priority  int
+	Targeting Targeting
+	// Weight is relative share of banner in weighted rotation, 1 when not set
+	Weight int
+	// DeletedAt is set while banner is soft deleted
+	DeletedAt time.Time
+	// Revision grows on every change, Save of existing banner must carry current one
+	Revision int64
 }
 
 // Service type
 type Service struct {
-	mu    sync.RWMutex
-	items []*Banner
+	mu           sync.RWMutex
+	items        []*Banner
+	trash        map[int64]*Banner
+	history      map[int64][]*Version
+	search       *searchIndex
+	events       *eventBus
+	store        storage.BlobStore
+	maxImageSize int64
+
+	rotationMu   sync.Mutex
+	random       *rand.Rand
+	cursors      map[uint64]rotationCursor
+	rotationTick uint64
+
+	metrics *serviceMetrics
 }
 
-// NewService construct
-func NewService() *Service {
-	return &Service{items: make([]*Banner, 0)}
+// NewService construct, images are kept in store (files in STORAGE when nil)
+// and may be at most maxImageSize bytes
+func NewService(store storage.BlobStore, maxImageSize int64) *Service {
+	if store == nil {
+		store = storage.NewFileStore(STORAGE, "/web/banners")
+	}
+	if maxImageSize <= 0 {
+		maxImageSize = MaxImageSize
+	}
+	return &Service{
+		items:        make([]*Banner, 0),
+		trash:        make(map[int64]*Banner),
+		history:      make(map[int64][]*Version),
+		search:       newSearchIndex(),
+		events:       newEventBus(),
+		store:        store,
+		maxImageSize: maxImageSize,
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		cursors:      make(map[uint64]rotationCursor),
+	}
+}
+
+// Store returns storage of banner images
+func (s *Service) Store() storage.BlobStore {
+	return s.store
+}
+
+// ImageSizeLimit returns maximum size of image in bytes
+func (s *Service) ImageSizeLimit() int64 {
+	return s.maxImageSize
 }
 
 // All simple implementation
@@ -43,7 +109,9 @@ func (s *Service) All(ctx context.Context) ([]*Banner, error) {
 }
 
 // ByID function to look for banner by id
-func (s *Service) ByID(ctx context.Context, id int64) (*Banner, error) {
+func (s *Service) ByID(ctx context.Context, id int64) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "by_id")
+	defer func() { end(err) }()
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	for _, banner := range s.items {
@@ -51,67 +119,193 @@ func (s *Service) ByID(ctx context.Context, id int64) (*Banner, error) {
 			return banner, nil
 		}
 	}
-	return nil, errors.New("banner by id not found")
+	return nil, ErrNotFound
 }
 
-// Save banner
-func (s *Service) Save(ctx context.Context, item *Banner, image multipart.File) (*Banner, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	if item.ID == 0 {
-		starID++
-		item.ID = starID
-		if item.Image != "" {
-			item.Image = fmt.Sprint(item.ID) + "." + item.Image
-			err := uploadFile(image, item)
-			if err != nil {
-				return nil, err
-			}
+// Save banner, image is optional and replaces current one when present
+func (s *Service) Save(ctx context.Context, item *Banner, image multipart.File) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "save")
+	defer func() { end(err) }()
+	if item.Status == "" {
+		item.Status = StatusDraft
+	}
+	if err := Validate(item); err != nil {
+		return nil, err
+	}
+
+	var upload *uploadedImage
+	if image != nil {
+		_, span := tracing.Start(ctx, "banners.read_image")
+		var err error
+		upload, err = readImage(image, s.maxImageSize)
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// image is stored without lock under keys of its own, lock is taken only to swap references
+	creating := item.ID == 0
+	var stored *storedImage
+	if upload != nil {
+		if creating {
+			item.ID = s.reserveID()
 		}
-		s.items = append(s.items, item)
-		return item, nil
-	}
-	for i, banner := range s.items {
-		if banner.ID == item.ID {
-			if item.Image != "" {
-				item.Image = fmt.Sprint(item.ID) + "." + item.Image
-				err := uploadFile(image, item)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				item.Image = s.items[i].Image
-			}
-			s.items[i] = item
-			return item, nil
+		stored, err = s.storeImage(ctx, upload, item.ID)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return nil, errors.New("banner save error")
+	s.mu.Lock()
+	result, obsolete, err := s.save(ctx, item, creating, stored)
+	s.mu.Unlock()
+	if err != nil && stored != nil {
+		obsolete = stored.keys()
+	}
+	s.removeImages(ctx, obsolete, nil)
+	return result, err
 }
 
-// RemoveByID banner to remove
-func (s *Service) RemoveByID(ctx context.Context, id int64) (*Banner, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	for i, banner := range s.items {
-		if banner.ID == id {
-			s.items = append(s.items[:i], s.items[i+1:]...)
-			return banner, nil
-		}
+// save creates or updates banner referencing stored image when it is given,
+// it returns image keys which are not referenced anymore; must be called under lock
+func (s *Service) save(ctx context.Context, item *Banner, creating bool, stored *storedImage) (*Banner, []string, error) {
+	if creating {
+		return s.create(ctx, item, stored), nil, nil
+	}
+	index := s.indexOf(item.ID)
+	if index == -1 {
+		return nil, nil, ErrNotFound
 	}
-	return nil, errors.New("banner remove by id not found")
+	if item.Revision != s.items[index].Revision {
+		return nil, nil, ErrConflict
+	}
+	result, obsolete := s.update(ctx, index, item, stored)
+	return result, obsolete, nil
+}
+
+// RemoveByID soft deletes banner, it can be restored until purged
+func (s *Service) RemoveByID(ctx context.Context, id int64) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "remove")
+	defer func() { end(err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := s.indexOf(id)
+	if index == -1 {
+		return nil, ErrNotFound
+	}
+	return s.remove(ctx, index), nil
+}
+
+// create gives valid item new id unless it is reserved and adds it with optional stored image;
+// must be called under lock
+func (s *Service) create(ctx context.Context, item *Banner, stored *storedImage) *Banner {
+	if item.ID == 0 {
+		starID++
+		item.ID = starID
+	}
+	stored.apply(item)
+	// id reserved before lock may be less than id of banner created in between
+	s.insert(item)
+	s.record(ctx, ActionCreate, nil, item)
+	return item
+}
+
+// update replaces banner at index by valid item, current image is kept unless stored one is given;
+// it returns keys of replaced image, must be called under lock
+func (s *Service) update(ctx context.Context, index int, item *Banner, stored *storedImage) (*Banner, []string) {
+	banner := s.items[index]
+	var obsolete []string
+	if stored != nil {
+		stored.apply(item)
+		obsolete = imageKeys(banner)
+	} else {
+		item.Image = banner.Image
+		item.Width = banner.Width
+		item.Height = banner.Height
+		item.Variants = banner.Variants
+	}
+	s.items[index] = item
+	s.record(ctx, ActionUpdate, banner, item)
+	return item, obsolete
+}
+
+// reserveID takes id for banner whose image is stored before it is created
+func (s *Service) reserveID() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	starID++
+	return starID
+}
+
+// remove moves banner at index to trash; must be called under lock
+func (s *Service) remove(ctx context.Context, index int) *Banner {
+	banner := s.items[index]
+	s.items = append(s.items[:index], s.items[index+1:]...)
+	deleted := banner.clone()
+	deleted.DeletedAt = time.Now()
+	s.trash[banner.ID] = deleted
+	s.record(ctx, ActionDelete, banner, deleted)
+	return deleted
 }
 
-func uploadFile(file multipart.File, banner *Banner) error {
-	var data, err = ioutil.ReadAll(file)
+// storeImage writes image and its variants of banner id under keys unique for this upload,
+// so it runs without lock and never overwrites image in use; keys must be removed when
+// banner does not take them
+func (s *Service) storeImage(ctx context.Context, upload *uploadedImage, id int64) (*storedImage, error) {
+	prefix := strconv.FormatInt(id, 10) + "-" + uploadToken()
+	stored := &storedImage{key: prefix + "." + upload.ext, width: upload.width, height: upload.height}
+	err := s.putImage(ctx, stored.key, bytes.NewReader(upload.data), int64(len(upload.data)), upload.mime)
 	if err != nil {
-		return errors.New("error read file")
+		logging.FromContext(ctx).Error("write image", "key", stored.key, "error", err)
+		return nil, errors.New("error to write file")
 	}
+	s.metrics.imageUploaded(len(upload.data))
 
-	err = ioutil.WriteFile(STORAGE+banner.Image, data, 0666)
+	stored.variants, err = s.uploadVariants(ctx, upload, prefix)
 	if err != nil {
-		return errors.New("error to write file")
+		s.removeImages(ctx, stored.keys(), nil)
+		return nil, err
+	}
+	return stored, nil
+}
+
+// removeImages deletes keys which are not among kept ones anymore,
+// failure is only logged because banner itself is already changed
+func (s *Service) removeImages(ctx context.Context, keys []string, kept []string) {
+	for _, key := range keys {
+		if containsString(kept, key) {
+			continue
+		}
+		_, span := tracing.Start(ctx, "banners.remove_image")
+		span.SetAttribute("image.key", key)
+		err := s.store.Delete(ctx, key)
+		span.SetError(err)
+		span.End()
+		if err != nil {
+			logging.FromContext(ctx).Warn("remove image", "key", key, "error", err)
+		}
+	}
+}
+
+// putImage stores image under key within its own span
+func (s *Service) putImage(ctx context.Context, key string, reader io.Reader, size int64, mimeType string) error {
+	ctx, span := tracing.Start(ctx, "banners.put_image")
+	defer span.End()
+	span.SetAttribute("image.key", key)
+	span.SetAttribute("image.size", size)
+	span.SetAttribute("image.mime", mimeType)
+	err := s.store.Put(ctx, key, reader, mimeType)
+	span.SetError(err)
+	return err
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-	return nil
+	return false
 }
@@ -0,0 +1,169 @@
+package banners
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+func TestMain(m *testing.M) {
+	logging.SetDefault(logging.New(ioutil.Discard, logging.LevelInfo, logging.FormatLogfmt))
+	os.Exit(m.Run())
+}
+
+// imageFile is uploaded image of multipart form
+type imageFile struct {
+	*bytes.Reader
+}
+
+func (imageFile) Close() error {
+	return nil
+}
+
+// gatedStore is memory store whose Put waits until gate is closed
+type gatedStore struct {
+	*storage.MemoryStore
+	started chan struct{}
+	gate    chan struct{}
+}
+
+func (s *gatedStore) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-s.gate
+	return s.MemoryStore.Put(ctx, key, data, contentType)
+}
+
+func sortedKeys(store *storage.MemoryStore) []string {
+	keys := store.Keys()
+	sort.Strings(keys)
+	return keys
+}
+
+func TestSaveStoresImageOutsideLock(t *testing.T) {
+	store := &gatedStore{
+		MemoryStore: storage.NewMemoryStore(""),
+		started:     make(chan struct{}, 1),
+		gate:        make(chan struct{}),
+	}
+	svc := NewService(store, 1<<20)
+	ctx := context.Background()
+	existing, err := svc.Save(ctx, &Banner{Title: "Sale", Content: "Half price"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	image := imageFile{bytes.NewReader(pngImage(t, 3, 2))}
+	saved := make(chan error, 1)
+	go func() {
+		_, err := svc.Save(ctx, &Banner{Title: "New", Content: "With image"}, image)
+		saved <- err
+	}()
+	<-store.started
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := svc.ByID(ctx, existing.ID)
+		if err == nil {
+			_, err = svc.All(ctx)
+		}
+		read <- err
+	}()
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Errorf("ByID() during upload = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("ByID() is blocked by upload")
+	}
+	// banner created during upload takes id greater than reserved one
+	if _, err := svc.Save(ctx, &Banner{Title: "Later", Content: "Without image"}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	close(store.gate)
+	if err := <-saved; err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+	items, _ := svc.All(ctx)
+	for i := 1; i < len(items); i++ {
+		if items[i-1].ID > items[i].ID {
+			t.Errorf("banner %d is listed before %d", items[i-1].ID, items[i].ID)
+		}
+	}
+}
+
+func TestSaveImageKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		// change turns saved banner with image into saved one
+		change  func(current *Banner) *Banner
+		err     error
+		replace bool
+	}{
+		{"update with image", func(current *Banner) *Banner {
+			return current.clone()
+		}, nil, true},
+		{"stale revision", func(current *Banner) *Banner {
+			item := current.clone()
+			item.Revision--
+			return item
+		}, ErrConflict, false},
+		{"missing banner", func(current *Banner) *Banner {
+			item := current.clone()
+			item.ID = current.ID + 100
+			return item
+		}, ErrNotFound, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := storage.NewMemoryStore("")
+			svc := NewService(store, 1<<20)
+			ctx := context.Background()
+			// 700 pixels wide image has 320 and 640 pixels wide variants
+			current, err := svc.Save(ctx, &Banner{Title: "Sale", Content: "Half price"}, imageFile{bytes.NewReader(pngImage(t, 700, 10))})
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := imageKeys(current)
+			sort.Strings(before)
+			if len(before) != 3 || !reflect.DeepEqual(sortedKeys(store), before) {
+				t.Fatalf("stored keys = %v, want image and two variants %v", sortedKeys(store), before)
+			}
+
+			result, err := svc.Save(ctx, test.change(current), imageFile{bytes.NewReader(pngImage(t, 700, 10))})
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Save() = %v, want %v", err, test.err)
+			}
+			want := before
+			if test.replace {
+				want = imageKeys(result)
+				sort.Strings(want)
+				for _, key := range want {
+					for _, old := range before {
+						if key == old {
+							t.Errorf("new image reuses key %q", key)
+						}
+					}
+				}
+			}
+			if got := sortedKeys(store); !reflect.DeepEqual(got, want) {
+				t.Errorf("stored keys = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package banners
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// Counters of impressions and clicks
+type Counters struct {
+	Impressions int64 `json:"impressions"`
+	Clicks      int64 `json:"clicks"`
+}
+
+// CTR is click-through rate, 0 when there are no impressions
+func (c Counters) CTR() float64 {
+	if c.Impressions == 0 {
+		return 0
+	}
+	return float64(c.Clicks) / float64(c.Impressions)
+}
+
+// Stats of one banner over time range
+type Stats struct {
+	BannerID int64 `json:"id"`
+	Counters
+	CTR float64 `json:"ctr"`
+}
+
+type statsKey struct {
+	bannerID int64
+	hour     int64
+}
+
+// StatsRetention is how long hourly counters are kept in memory, older ones are dropped
+// by Flush once they are written to store
+const StatsRetention = 90 * 24 * time.Hour
+
+// Tracker aggregates impressions and clicks in memory into per-banner, per-hour counters
+// and flushes touched hours to store as stats/<yyyymmddhh>.json
+type Tracker struct {
+	mu     sync.Mutex
+	totals map[statsKey]Counters
+	// touched are hours changed since their last flush, value grows with every change
+	touched map[int64]uint64
+	changes uint64
+	store   storage.BlobStore
+	now     func() time.Time
+}
+
+// NewTracker creates tracker flushing to store
+func NewTracker(store storage.BlobStore) *Tracker {
+	return &Tracker{
+		totals:  make(map[statsKey]Counters),
+		touched: make(map[int64]uint64),
+		store:   store,
+		now:     time.Now,
+	}
+}
+
+// RecordImpression counts one view of banner
+func (t *Tracker) RecordImpression(ctx context.Context, bannerID int64) {
+	t.record(bannerID, 1, 0)
+}
+
+// RecordClick counts one click on banner
+func (t *Tracker) RecordClick(ctx context.Context, bannerID int64) {
+	t.record(bannerID, 0, 1)
+}
+
+func (t *Tracker) record(bannerID int64, impressions int64, clicks int64) {
+	hour := hourOf(t.now())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := statsKey{bannerID: bannerID, hour: hour}
+	counters := t.totals[key]
+	counters.Impressions += impressions
+	counters.Clicks += clicks
+	t.totals[key] = counters
+	t.changes++
+	t.touched[hour] = t.changes
+}
+
+// Counters sums counters of banner over hours intersecting [from, to)
+func (t *Tracker) Counters(ctx context.Context, bannerID int64, from time.Time, to time.Time) Counters {
+	fromHour, toHour := hourOf(from), hourOf(to.Add(-time.Nanosecond))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var sum Counters
+	for key, counters := range t.totals {
+		if key.bannerID == bannerID && key.hour >= fromHour && key.hour <= toHour {
+			sum.Impressions += counters.Impressions
+			sum.Clicks += counters.Clicks
+		}
+	}
+	return sum
+}
+
+// Stats returns counters of every banner seen over hours intersecting [from, to), ordered by id
+func (t *Tracker) Stats(ctx context.Context, from time.Time, to time.Time) []Stats {
+	fromHour, toHour := hourOf(from), hourOf(to.Add(-time.Nanosecond))
+
+	t.mu.Lock()
+	sums := make(map[int64]Counters)
+	for key, counters := range t.totals {
+		if key.hour >= fromHour && key.hour <= toHour {
+			sum := sums[key.bannerID]
+			sum.Impressions += counters.Impressions
+			sum.Clicks += counters.Clicks
+			sums[key.bannerID] = sum
+		}
+	}
+	t.mu.Unlock()
+
+	stats := make([]Stats, 0, len(sums))
+	for id, counters := range sums {
+		stats = append(stats, Stats{BannerID: id, Counters: counters, CTR: counters.CTR()})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].BannerID < stats[j].BannerID
+	})
+	return stats
+}
+
+// Flush writes every hour changed since its previous flush to store, hour stays touched
+// until it is written, so hours not written because of error are written by next flush;
+// first error is returned. Then it drops written hours older than StatsRetention from memory.
+func (t *Tracker) Flush(ctx context.Context) (err error) {
+	t.mu.Lock()
+	changes := make(map[int64]uint64, len(t.touched))
+	snapshots := make(map[int64]map[int64]Counters, len(t.touched))
+	for hour, change := range t.touched {
+		changes[hour] = change
+		snapshots[hour] = make(map[int64]Counters)
+	}
+	for key, counters := range t.totals {
+		if snapshot, ok := snapshots[key.hour]; ok {
+			snapshot[key.bannerID] = counters
+		}
+	}
+	t.mu.Unlock()
+
+	for hour, snapshot := range snapshots {
+		data, marshalErr := json.Marshal(snapshot)
+		if marshalErr == nil {
+			marshalErr = t.store.Put(ctx, statsBlobKey(hour), bytes.NewReader(data), "application/json")
+		}
+		if marshalErr != nil {
+			if err == nil {
+				err = marshalErr
+			}
+			continue
+		}
+
+		// hour changed during Put stays touched
+		t.mu.Lock()
+		if t.touched[hour] == changes[hour] {
+			delete(t.touched, hour)
+		}
+		t.mu.Unlock()
+	}
+
+	t.prune(hourOf(t.now().Add(-StatsRetention)))
+	return err
+}
+
+// prune drops counters of written hours before oldest
+func (t *Tracker) prune(oldest int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range t.totals {
+		if _, touched := t.touched[key.hour]; key.hour < oldest && !touched {
+			delete(t.totals, key)
+		}
+	}
+}
+
+// Load reads flushed hours intersecting [from, to) back into memory,
+// counters recorded after restart are added to loaded ones
+func (t *Tracker) Load(ctx context.Context, from time.Time, to time.Time) error {
+	for hour := hourOf(from); hour <= hourOf(to.Add(-time.Nanosecond)); hour++ {
+		reader, err := t.store.Get(ctx, statsBlobKey(hour))
+		if err == storage.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		snapshot := make(map[int64]Counters)
+		err = json.NewDecoder(reader).Decode(&snapshot)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+
+		t.mu.Lock()
+		for bannerID, counters := range snapshot {
+			key := statsKey{bannerID: bannerID, hour: hour}
+			current := t.totals[key]
+			current.Impressions += counters.Impressions
+			current.Clicks += counters.Clicks
+			t.totals[key] = current
+		}
+		t.mu.Unlock()
+	}
+	return nil
+}
+
+// Run flushes counters every interval until ctx is done, then flushes last time
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				logging.FromContext(ctx).Error("flush stats", "error", err)
+			}
+		case <-ctx.Done():
+			if err := t.Flush(context.Background()); err != nil {
+				logging.FromContext(ctx).Error("flush stats", "error", err)
+			}
+			return
+		}
+	}
+}
+
+func hourOf(moment time.Time) int64 {
+	return moment.Unix() / 3600
+}
+
+func statsBlobKey(hour int64) string {
+	return "stats/" + time.Unix(hour*3600, 0).UTC().Format("2006010215") + ".json"
+}
@@ -0,0 +1,149 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// flakyStore is memory store failing Put of keys in failing, it remembers keys of all Puts
+type flakyStore struct {
+	*storage.MemoryStore
+	failing map[string]bool
+	puts    []string
+}
+
+func (s *flakyStore) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	s.puts = append(s.puts, key)
+	if s.failing[key] {
+		return errors.New("store is unavailable")
+	}
+	return s.MemoryStore.Put(ctx, key, data, contentType)
+}
+
+func TestTrackerFlush(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	old := now.Add(-StatsRetention - 2*time.Hour)
+
+	tests := []struct {
+		name string
+		// recorded are touched hours, all of them are written by first flush
+		recorded []time.Time
+		failing  []time.Time
+		// secondPuts are hours written by second flush
+		secondPuts []time.Time
+		// kept are hours whose counters stay in memory after first flush
+		kept []time.Time
+	}{
+		{
+			name:     "touched hours are written once",
+			recorded: []time.Time{now, now.Add(-time.Hour)},
+			kept:     []time.Time{now.Add(-time.Hour), now},
+		},
+		{
+			name:       "failed hour is written by next flush",
+			recorded:   []time.Time{now, now.Add(-time.Hour), now.Add(-2 * time.Hour)},
+			failing:    []time.Time{now.Add(-time.Hour)},
+			secondPuts: []time.Time{now.Add(-time.Hour)},
+			kept:       []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Hour), now},
+		},
+		{
+			name:     "written hours beyond retention are dropped",
+			recorded: []time.Time{old, now},
+			kept:     []time.Time{now},
+		},
+		{
+			name:       "unwritten hours beyond retention are kept",
+			recorded:   []time.Time{old, now},
+			failing:    []time.Time{old},
+			secondPuts: []time.Time{old},
+			kept:       []time.Time{old, now},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &flakyStore{MemoryStore: storage.NewMemoryStore(""), failing: make(map[string]bool)}
+			for _, moment := range test.failing {
+				store.failing[statsBlobKey(hourOf(moment))] = true
+			}
+			tracker := NewTracker(store)
+			tracker.now = func() time.Time { return now }
+			for _, moment := range test.recorded {
+				tracker.totals[statsKey{bannerID: 1, hour: hourOf(moment)}] = Counters{Impressions: 1}
+				tracker.touched[hourOf(moment)] = 1
+			}
+
+			err := tracker.Flush(context.Background())
+			if (err != nil) != (len(test.failing) != 0) {
+				t.Fatalf("Flush() = %v", err)
+			}
+			firstPuts := store.puts
+			var kept []string
+			for key := range tracker.totals {
+				kept = append(kept, statsBlobKey(key.hour))
+			}
+			store.puts, store.failing = nil, nil
+			if err := tracker.Flush(context.Background()); err != nil {
+				t.Fatalf("second Flush() = %v", err)
+			}
+
+			if !reflect.DeepEqual(statsKeys(firstPuts), hourKeys(test.recorded)) {
+				t.Errorf("first flush wrote %v, want %v", firstPuts, hourKeys(test.recorded))
+			}
+			if !reflect.DeepEqual(statsKeys(store.puts), hourKeys(test.secondPuts)) {
+				t.Errorf("second flush wrote %v, want %v", store.puts, hourKeys(test.secondPuts))
+			}
+			if !reflect.DeepEqual(statsKeys(kept), hourKeys(test.kept)) {
+				t.Errorf("kept %v, want %v", kept, hourKeys(test.kept))
+			}
+		})
+	}
+}
+
+func TestTrackerLoad(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+	store := storage.NewMemoryStore("")
+	tracker := NewTracker(store)
+	tracker.now = func() time.Time { return now }
+	tracker.RecordImpression(context.Background(), 1)
+	tracker.RecordImpression(context.Background(), 1)
+	tracker.RecordClick(context.Background(), 1)
+	if err := tracker.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewTracker(store)
+	restarted.now = tracker.now
+	restarted.RecordImpression(context.Background(), 1)
+	if err := restarted.Load(context.Background(), now.Add(-time.Hour), now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	got := restarted.Counters(context.Background(), 1, now.Add(-time.Hour), now.Add(time.Hour))
+	if want := (Counters{Impressions: 3, Clicks: 1}); got != want {
+		t.Errorf("Counters() = %+v, want %+v", got, want)
+	}
+}
+
+func statsKeys(keys []string) []string {
+	if len(keys) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func hourKeys(moments []time.Time) []string {
+	keys := make([]string, len(moments))
+	for i, moment := range moments {
+		keys[i] = statsBlobKey(hourOf(moment))
+	}
+	return statsKeys(keys)
+}
@@ -0,0 +1,220 @@
+package banners
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxRotationCursors limits number of candidate sets whose round robin position is remembered
+const MaxRotationCursors = 1024
+
+// ErrNoBanner is returned by Pick when no banner fits visitor
+var ErrNoBanner = errors.New("no banner matches context")
+
+// Device is class of visitor device
+type Device string
+
+// Supported device classes
+const (
+	DeviceDesktop Device = "desktop"
+	DeviceMobile  Device = "mobile"
+	DeviceTablet  Device = "tablet"
+)
+
+// Valid reports whether device is one of supported
+func (d Device) Valid() bool {
+	switch d {
+	case DeviceDesktop, DeviceMobile, DeviceTablet:
+		return true
+	}
+	return false
+}
+
+// Rotation is strategy of choosing one banner among matching ones
+type Rotation string
+
+// Supported rotations
+const (
+	RotationWeighted   Rotation = "weighted"
+	RotationRoundRobin Rotation = "round_robin"
+)
+
+// Targeting restricts audience of banner, empty rule matches everybody
+type Targeting struct {
+	// Locales are language tags, "en" matches "en-US" too
+	Locales    []string
+	Devices    []Device
+	PathPrefix string
+	// Segments must all be present with same values in visitor segments
+	Segments map[string]string
+}
+
+// Context describes visitor for which banner is picked
+type Context struct {
+	// Locales are preferred locales of visitor, most preferred first
+	Locales  []string
+	Device   Device
+	Path     string
+	Segments map[string]string
+	Rotation Rotation
+	// Seed makes weighted rotation deterministic when not zero
+	Seed int64
+	// Now is time of request, current time when zero
+	Now time.Time
+}
+
+// Matches reports whether visitor is in audience of targeting
+func (t *Targeting) Matches(c Context) bool {
+	if len(t.Locales) != 0 && !matchLocale(t.Locales, c.Locales) {
+		return false
+	}
+	if len(t.Devices) != 0 {
+		found := false
+		for _, device := range t.Devices {
+			if device == c.Device {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if t.PathPrefix != "" && !strings.HasPrefix(c.Path, t.PathPrefix) {
+		return false
+	}
+	for key, value := range t.Segments {
+		if actual, ok := c.Segments[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+func matchLocale(targets []string, locales []string) bool {
+	for _, locale := range locales {
+		locale = strings.ToLower(locale)
+		for _, target := range targets {
+			target = strings.ToLower(target)
+			if locale == target || strings.HasPrefix(locale, target+"-") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Pick chooses one of active banners matching visitor
+func (s *Service) Pick(ctx context.Context, c Context) (result *Banner, err error) {
+	ctx, end := s.begin(ctx, "pick")
+	defer func() { end(err) }()
+	now := c.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	active, err := s.Active(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]*Banner, 0, len(active))
+	for _, banner := range active {
+		if banner.Targeting.Matches(c) {
+			candidates = append(candidates, banner)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoBanner
+	}
+
+	if c.Rotation == RotationRoundRobin {
+		return s.pickRoundRobin(candidates), nil
+	}
+	return s.pickWeighted(candidates, c.Seed), nil
+}
+
+// pickWeighted chooses banner with probability proportional to its weight,
+// weights are summed as int64 so that their total stays positive
+func (s *Service) pickWeighted(candidates []*Banner, seed int64) *Banner {
+	var total int64
+	for _, banner := range candidates {
+		total += int64(banner.weight())
+	}
+
+	var n int64
+	if seed != 0 {
+		n = rand.New(rand.NewSource(seed)).Int63n(total)
+	} else {
+		s.rotationMu.Lock()
+		n = s.random.Int63n(total)
+		s.rotationMu.Unlock()
+	}
+
+	for _, banner := range candidates {
+		n -= int64(banner.weight())
+		if n < 0 {
+			return banner
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// pickRoundRobin cycles over candidates in order of ids, every distinct set of candidates has
+// own cursor; at most MaxRotationCursors sets are remembered, least recently used is forgotten
+func (s *Service) pickRoundRobin(candidates []*Banner) *Banner {
+	sorted := append([]*Banner(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	hash := fnv.New64a()
+	for _, banner := range sorted {
+		hash.Write([]byte(strconv.FormatInt(banner.ID, 10) + ","))
+	}
+	key := hash.Sum64()
+
+	s.rotationMu.Lock()
+	defer s.rotationMu.Unlock()
+	s.rotationTick++
+	cursor, ok := s.cursors[key]
+	if !ok && len(s.cursors) >= MaxRotationCursors {
+		s.forgetCursor()
+	}
+	s.cursors[key] = rotationCursor{next: cursor.next + 1, used: s.rotationTick}
+	return sorted[cursor.next%uint64(len(sorted))]
+}
+
+// rotationCursor is position of round robin rotation over set of candidates
+type rotationCursor struct {
+	next uint64
+	// used is tick of last pick from set
+	used uint64
+}
+
+// forgetCursor removes least recently used cursor; must be called under rotationMu
+func (s *Service) forgetCursor() {
+	var oldest uint64
+	var oldestUsed uint64 = math.MaxUint64
+	for key, cursor := range s.cursors {
+		if cursor.used < oldestUsed {
+			oldest, oldestUsed = key, cursor.used
+		}
+	}
+	delete(s.cursors, oldest)
+}
+
+// weight of banner in rotation, it is clamped to MaxWeight for banners which were never validated
+func (b *Banner) weight() int {
+	switch {
+	case b.Weight <= 0:
+		return 1
+	case b.Weight > MaxWeight:
+		return MaxWeight
+	}
+	return b.Weight
+}
@@ -0,0 +1,181 @@
+package banners
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+func TestTargetingMatches(t *testing.T) {
+	visitor := Context{
+		Locales:  []string{"de-AT", "en-US"},
+		Device:   DeviceMobile,
+		Path:     "/shop/shoes",
+		Segments: map[string]string{"plan": "pro", "country": "at"},
+	}
+
+	tests := []struct {
+		name      string
+		targeting Targeting
+		want      bool
+	}{
+		{"empty", Targeting{}, true},
+		{"locale prefix", Targeting{Locales: []string{"EN"}}, true},
+		{"other locale", Targeting{Locales: []string{"fr", "en-GB"}}, false},
+		{"device", Targeting{Devices: []Device{DeviceTablet, DeviceMobile}}, true},
+		{"other device", Targeting{Devices: []Device{DeviceDesktop}}, false},
+		{"path prefix", Targeting{PathPrefix: "/shop/"}, true},
+		{"other path", Targeting{PathPrefix: "/blog"}, false},
+		{"segments", Targeting{Segments: map[string]string{"plan": "pro"}}, true},
+		{"other segment value", Targeting{Segments: map[string]string{"plan": "free"}}, false},
+		{"missing segment", Targeting{Segments: map[string]string{"age": "18"}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.targeting.Matches(visitor); got != test.want {
+				t.Errorf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// publishedService returns service with published banners of weights
+func publishedService(t *testing.T, weights ...int) *Service {
+	t.Helper()
+	svc := NewService(storage.NewMemoryStore(""), 1<<20)
+	for _, weight := range weights {
+		item := &Banner{Title: "Sale", Content: "Half price", Status: StatusPublished, Weight: weight}
+		if _, err := svc.Save(context.Background(), item, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc
+}
+
+func TestPickWeightedSeed(t *testing.T) {
+	svc := publishedService(t, 1, 5, 1)
+
+	tests := []struct {
+		name string
+		seed int64
+	}{
+		{"seed 1", 1},
+		{"seed 42", 42},
+		{"negative seed", -7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Context{Seed: test.seed}
+			first, err := svc.Pick(context.Background(), c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 10; i++ {
+				if next, _ := svc.Pick(context.Background(), c); next != first {
+					t.Fatalf("Pick() = %d, want %d picked before with same seed", next.ID, first.ID)
+				}
+			}
+		})
+	}
+
+	active, _ := svc.Active(context.Background(), time.Now())
+	picks := make(map[int64]int)
+	for seed := int64(1); seed <= 700; seed++ {
+		picks[svc.pickWeighted(active, seed).ID]++
+	}
+	heavy := active[1].ID
+	if picks[heavy] < 400 || picks[heavy] > 600 {
+		t.Errorf("banner of weight 5 is picked %d times of 700, want about 500", picks[heavy])
+	}
+}
+
+func TestPickWeightedLargeWeights(t *testing.T) {
+	maxInt := int(^uint(0) >> 1)
+	svc := NewService(storage.NewMemoryStore(""), 0)
+
+	tests := []struct {
+		name    string
+		weights []int
+		// heavy is index of banner picked at least 90 of 100 times, -1 when picks are spread
+		heavy int
+	}{
+		{"max weights", []int{MaxWeight, MaxWeight, MaxWeight}, -1},
+		{"overflowing weights", []int{maxInt, maxInt, maxInt}, -1},
+		{"overflowing weight among small ones", []int{1, maxInt, 1}, 1},
+		{"zero and negative weights", []int{0, -5, 0}, -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			candidates := make([]*Banner, len(test.weights))
+			for i, weight := range test.weights {
+				candidates[i] = &Banner{ID: int64(i + 1), Weight: weight}
+			}
+			picks := make(map[int64]int)
+			for seed := int64(1); seed <= 100; seed++ {
+				picks[svc.pickWeighted(candidates, seed).ID]++
+			}
+			for i, banner := range candidates {
+				if test.heavy == i && picks[banner.ID] < 90 || test.heavy == -1 && picks[banner.ID] < 10 {
+					t.Errorf("banner %d is picked %d times of 100", i, picks[banner.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestPickRoundRobin(t *testing.T) {
+	svc := publishedService(t, 1, 1, 1)
+	active, _ := svc.Active(context.Background(), time.Now())
+	reversed := []*Banner{active[2], active[1], active[0]}
+
+	tests := []struct {
+		name       string
+		candidates []*Banner
+		want       []int
+	}{
+		{"all", active, []int{0, 1, 2, 0}},
+		{"same set in other order continues", reversed, []int{1, 2}},
+		{"other set has own cursor", active[1:], []int{1, 2, 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got, want []int64
+			for _, i := range test.want {
+				got = append(got, svc.pickRoundRobin(test.candidates).ID)
+				want = append(want, active[i].ID)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("picked %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRotationCursorsAreBounded(t *testing.T) {
+	svc := NewService(storage.NewMemoryStore(""), 1<<20)
+	first := []*Banner{{ID: 1}, {ID: 2}}
+	svc.pickRoundRobin(first)
+	picks := 1
+	for id := int64(10); id < 10+2*MaxRotationCursors; id++ {
+		svc.pickRoundRobin([]*Banner{{ID: id}})
+		if id%100 == 0 {
+			// first set is used often, so it is never forgotten
+			svc.pickRoundRobin(first)
+			picks++
+		}
+	}
+
+	if len(svc.cursors) > MaxRotationCursors {
+		t.Errorf("%d cursors are kept, want at most %d", len(svc.cursors), MaxRotationCursors)
+	}
+	if got, want := svc.pickRoundRobin(first).ID, first[picks%2].ID; got != want {
+		t.Errorf("picked %d after %d picks, want %d", got, picks, want)
+	}
+}
@@ -0,0 +1,18 @@
+package banners
+
+import (
+	"context"
+
+	"github.com/MrHakimov/http/pkg/tracing"
+)
+
+// begin starts span of operation when ctx is traced, returned function ends it
+// and counts operation in metrics
+func (s *Service) begin(ctx context.Context, operation string) (context.Context, func(err error)) {
+	ctx, span := tracing.Start(ctx, "banners."+operation)
+	return ctx, func(err error) {
+		s.metrics.operation(operation, err)
+		span.SetError(err)
+		span.End()
+	}
+}
@@ -0,0 +1,244 @@
+package banners
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ImportMode tells what happens to IDs of imported banners
+type ImportMode string
+
+// Supported import modes
+const (
+	// ImportUpsert keeps IDs: banner with same ID is replaced, missing one is created with that ID
+	// and banner without ID is created with new one
+	ImportUpsert ImportMode = "upsert"
+	// ImportRemap creates every banner with new ID, IDs of rows are only reported back
+	ImportRemap ImportMode = "remap"
+)
+
+// Valid reports whether mode is supported
+func (m ImportMode) Valid() bool {
+	return m == ImportUpsert || m == ImportRemap
+}
+
+// ErrImportInvalid is returned by Import when some row is invalid, nothing is imported then
+var ErrImportInvalid = errors.New("import has invalid rows")
+
+// ImportRow is one banner being imported
+type ImportRow struct {
+	// Row is number of row in source, e.g. line of JSON Lines file
+	Row    int
+	Banner *Banner
+	// Image is content of image file, banner keeps its current image when it is nil
+	Image []byte
+	// Errors are found while parsing row, such row is reported and not imported
+	Errors []FieldError
+}
+
+// ImportResult is outcome of one row, ID is 0 for rows created by dry run in remap mode
+type ImportResult struct {
+	Row      int          `json:"row"`
+	SourceID int64        `json:"source_id,omitempty"`
+	ID       int64        `json:"id,omitempty"`
+	Action   Action       `json:"action,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// ImportReport is outcome of import
+type ImportReport struct {
+	Mode    ImportMode     `json:"mode"`
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Failed  int            `json:"failed"`
+	Rows    []ImportResult `json:"rows"`
+}
+
+// Import validates every row and, unless it is dry run or some row is invalid, saves all of them.
+// Invalid rows make Import return report with their errors together with ErrImportInvalid.
+// Import is all or nothing: images are decoded and stored without lock and banners are
+// changed only when every row can be applied, otherwise stored images are removed again.
+func (s *Service) Import(ctx context.Context, rows []ImportRow, mode ImportMode, dryRun bool) (report *ImportReport, err error) {
+	ctx, end := s.begin(ctx, "import")
+	defer func() { end(err) }()
+	if !mode.Valid() {
+		return nil, errors.New("unknown import mode " + strconv.Quote(string(mode)))
+	}
+
+	report = &ImportReport{Mode: mode, DryRun: dryRun, Rows: make([]ImportResult, len(rows))}
+	uploads := make([]*uploadedImage, len(rows))
+	seen := make(map[int64]int)
+	for i, row := range rows {
+		result := ImportResult{Row: row.Row, Errors: row.Errors}
+		if row.Banner != nil {
+			result.SourceID = row.Banner.ID
+		}
+		if len(result.Errors) == 0 {
+			result.Errors, uploads[i] = s.validateImport(row, mode, seen)
+		}
+		if len(result.Errors) != 0 {
+			report.Failed++
+		}
+		report.Rows[i] = result
+	}
+	s.mu.RLock()
+	s.checkImport(rows, mode, report)
+	s.mu.RUnlock()
+	if report.Failed != 0 {
+		return report, ErrImportInvalid
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	// banners created with new ID get it reserved in row order, so their images are stored under it
+	stored := make([]*storedImage, len(rows))
+	reserved := make([]int64, len(rows))
+	for i, row := range rows {
+		if mode == ImportRemap || row.Banner.ID == 0 {
+			reserved[i] = s.reserveID()
+		}
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, image := range stored {
+			if image != nil {
+				s.removeImages(ctx, image.keys(), nil)
+			}
+		}
+	}()
+	for i, upload := range uploads {
+		if upload == nil {
+			continue
+		}
+		id := rows[i].Banner.ID
+		if reserved[i] != 0 {
+			id = reserved[i]
+		}
+		if stored[i], err = s.storeImage(ctx, upload, id); err != nil {
+			return report, err
+		}
+	}
+
+	// banners may have changed since rows were checked, so they are checked again before commit
+	s.mu.Lock()
+	s.checkImport(rows, mode, report)
+	var obsolete []string
+	if report.Failed == 0 {
+		for i, row := range rows {
+			var replaced []string
+			report.Rows[i].ID, replaced = s.importRow(ctx, row.Banner, stored[i], reserved[i])
+			obsolete = append(obsolete, replaced...)
+		}
+	}
+	s.mu.Unlock()
+	if report.Failed != 0 {
+		return report, ErrImportInvalid
+	}
+	s.removeImages(ctx, obsolete, nil)
+	return report, nil
+}
+
+// validateImport returns errors of row and its decoded image, it does not look at current banners
+func (s *Service) validateImport(row ImportRow, mode ImportMode, seen map[int64]int) ([]FieldError, *uploadedImage) {
+	if row.Banner == nil {
+		return []FieldError{{Field: "row", Message: "is empty"}}, nil
+	}
+
+	var errs []FieldError
+	item := row.Banner
+	if item.Status == "" {
+		item.Status = StatusDraft
+	}
+	var verr *ValidationError
+	if err := Validate(item); errors.As(err, &verr) {
+		errs = append(errs, verr.Errors...)
+	}
+
+	if mode == ImportUpsert && item.ID != 0 {
+		switch {
+		case item.ID < 0:
+			errs = append(errs, FieldError{Field: "id", Message: "must be positive"})
+		case seen[item.ID] != 0:
+			errs = append(errs, FieldError{Field: "id", Message: "duplicates row " + strconv.Itoa(seen[item.ID])})
+		}
+		if seen[item.ID] == 0 {
+			seen[item.ID] = row.Row
+		}
+	}
+
+	var upload *uploadedImage
+	if row.Image != nil {
+		var err error
+		upload, err = readImage(bytes.NewReader(row.Image), s.maxImageSize)
+		if errors.As(err, &verr) {
+			errs = append(errs, verr.Errors...)
+		} else if err != nil {
+			errs = append(errs, FieldError{Field: "image", Message: err.Error()})
+		}
+	}
+	return errs, upload
+}
+
+// checkImport checks valid rows against current banners and counts what they do;
+// must be called under lock
+func (s *Service) checkImport(rows []ImportRow, mode ImportMode, report *ImportReport) {
+	report.Created, report.Updated = 0, 0
+	for i, row := range rows {
+		result := &report.Rows[i]
+		if len(result.Errors) != 0 {
+			continue
+		}
+		upsert := mode == ImportUpsert && row.Banner.ID != 0
+		if upsert && s.trash[row.Banner.ID] != nil {
+			result.Errors = []FieldError{{Field: "id", Message: "banner is deleted, restore or purge it first"}}
+			result.Action = ""
+			report.Failed++
+			continue
+		}
+		result.Action = ActionCreate
+		if upsert && s.indexOf(row.Banner.ID) != -1 {
+			result.Action = ActionUpdate
+		}
+		if mode == ImportUpsert {
+			result.ID = row.Banner.ID
+		}
+		if result.Action == ActionUpdate {
+			report.Updated++
+		} else {
+			report.Created++
+		}
+	}
+}
+
+// importRow saves checked banner with optional stored image and returns its ID together with
+// keys of replaced image; id is reserved for created banner or 0 for upserted one, must be called under lock
+func (s *Service) importRow(ctx context.Context, item *Banner, stored *storedImage, id int64) (int64, []string) {
+	item = item.clone()
+	item.DeletedAt = time.Time{}
+	index := -1
+	if id != 0 {
+		item.ID = id
+	} else {
+		index = s.indexOf(item.ID)
+		if item.ID > starID {
+			starID = item.ID
+		}
+	}
+
+	if index != -1 {
+		item, obsolete := s.update(ctx, index, item, stored)
+		return item.ID, obsolete
+	}
+	item.Image, item.Width, item.Height, item.Variants = "", 0, 0, nil
+	stored.apply(item)
+	s.insert(item)
+	s.record(ctx, ActionCreate, nil, item)
+	return item.ID, nil
+}
@@ -0,0 +1,180 @@
+package banners
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// limitedStore is memory store failing every Put after first allowed ones
+type limitedStore struct {
+	*storage.MemoryStore
+	allowed int
+}
+
+func (s *limitedStore) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	if s.allowed == 0 {
+		return errors.New("store is unavailable")
+	}
+	s.allowed--
+	return s.MemoryStore.Put(ctx, key, data, contentType)
+}
+
+func TestImportAllOrNothing(t *testing.T) {
+	tests := []struct {
+		name string
+		// rows are imported after banner existing with image and deleted one are saved
+		rows    func(existing, deleted *Banner, image []byte) []ImportRow
+		mode    ImportMode
+		dryRun  bool
+		allowed int
+		err     bool
+		// applied is whether banners are changed, created and updated are counts of report
+		applied          bool
+		created, updated int
+	}{
+		{"valid rows", func(existing, _ *Banner, image []byte) []ImportRow {
+			return []ImportRow{
+				{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}, Image: image},
+				{Row: 2, Banner: &Banner{Title: "Created", Content: "Text"}, Image: image},
+			}
+		}, ImportUpsert, false, 10, false, true, 1, 1},
+		{"remap", func(existing, _ *Banner, image []byte) []ImportRow {
+			return []ImportRow{{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Copy", Content: "Text"}, Image: image}}
+		}, ImportRemap, false, 10, false, true, 1, 0},
+		{"dry run", func(existing, _ *Banner, image []byte) []ImportRow {
+			return []ImportRow{{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}, Image: image}}
+		}, ImportUpsert, true, 10, false, false, 0, 1},
+		{"invalid row", func(existing, _ *Banner, image []byte) []ImportRow {
+			return []ImportRow{
+				{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}, Image: image},
+				{Row: 2, Banner: &Banner{Content: "Text"}},
+			}
+		}, ImportUpsert, false, 10, true, false, 0, 0},
+		{"invalid image", func(existing, _ *Banner, image []byte) []ImportRow {
+			return []ImportRow{
+				{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}, Image: image},
+				{Row: 2, Banner: &Banner{Title: "Created", Content: "Text"}, Image: []byte("not an image")},
+			}
+		}, ImportUpsert, false, 10, true, false, 0, 0},
+		{"deleted banner", func(existing, deleted *Banner, _ []byte) []ImportRow {
+			return []ImportRow{
+				{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}},
+				{Row: 2, Banner: &Banner{ID: deleted.ID, Title: "Back", Content: "Text"}},
+			}
+		}, ImportUpsert, false, 10, true, false, 0, 0},
+		{"duplicate id", func(existing, _ *Banner, _ []byte) []ImportRow {
+			return []ImportRow{
+				{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}},
+				{Row: 2, Banner: &Banner{ID: existing.ID, Title: "Again", Content: "Text"}},
+			}
+		}, ImportUpsert, false, 10, true, false, 0, 0},
+		{"failed image store", func(existing, _ *Banner, image []byte) []ImportRow {
+			return []ImportRow{
+				{Row: 1, Banner: &Banner{ID: existing.ID, Title: "Updated", Content: "Text"}, Image: image},
+				{Row: 2, Banner: &Banner{Title: "Created", Content: "Text"}, Image: image},
+			}
+		}, ImportUpsert, false, 1, true, false, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &limitedStore{MemoryStore: storage.NewMemoryStore(""), allowed: 2}
+			svc := NewService(store, 1<<20)
+			ctx := context.Background()
+			image := pngImage(t, 3, 2)
+			existing, err := svc.Save(ctx, &Banner{Title: "Sale", Content: "Half price"}, imageFile{bytes.NewReader(image)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			deleted, err := svc.Save(ctx, &Banner{Title: "Old", Content: "Gone"}, imageFile{bytes.NewReader(image)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = svc.RemoveByID(ctx, deleted.ID); err != nil {
+				t.Fatal(err)
+			}
+			store.allowed = test.allowed
+			keys := sortedKeys(store.MemoryStore)
+			items, _ := svc.All(ctx)
+			before := append([]*Banner(nil), items...)
+
+			report, err := svc.Import(ctx, test.rows(existing, deleted, image), test.mode, test.dryRun)
+			if (err != nil) != test.err {
+				t.Fatalf("Import() = %v, want error: %v", err, test.err)
+			}
+			if report != nil && !test.err && (report.Created != test.created || report.Updated != test.updated) {
+				t.Errorf("report = %d created, %d updated, want %d, %d", report.Created, report.Updated, test.created, test.updated)
+			}
+
+			items, _ = svc.All(ctx)
+			if !test.applied {
+				if !reflect.DeepEqual(items, before) {
+					t.Errorf("banners changed by import which is not applied")
+				}
+				if !reflect.DeepEqual(sortedKeys(store.MemoryStore), keys) {
+					t.Errorf("stored keys = %v, want %v", sortedKeys(store.MemoryStore), keys)
+				}
+				return
+			}
+
+			// replaced image is removed and every banner references stored image
+			var referenced []string
+			for _, item := range items {
+				referenced = append(referenced, imageKeys(item)...)
+			}
+			referenced = append(referenced, imageKeys(svc.trash[deleted.ID])...)
+			for _, key := range referenced {
+				if _, err := store.Get(ctx, key); err != nil {
+					t.Errorf("image %s of banner is not stored", key)
+				}
+			}
+			if len(sortedKeys(store.MemoryStore)) != len(referenced) {
+				t.Errorf("stored keys = %v, want only referenced %v", sortedKeys(store.MemoryStore), referenced)
+			}
+			for i, result := range report.Rows {
+				if result.ID == 0 || test.mode == ImportRemap && result.ID == result.SourceID {
+					t.Errorf("row %d has id %d of source %d", i+1, result.ID, result.SourceID)
+				}
+			}
+		})
+	}
+}
+
+func TestImportIDsFollowRows(t *testing.T) {
+	tests := []struct {
+		name string
+		mode ImportMode
+	}{
+		{"upsert", ImportUpsert},
+		{"remap", ImportRemap},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			svc := NewService(storage.NewMemoryStore(""), 1<<20)
+			image := pngImage(t, 3, 2)
+			rows := []ImportRow{
+				{Row: 1, Banner: &Banner{Title: "First", Content: "Text"}},
+				{Row: 2, Banner: &Banner{Title: "Second", Content: "Text"}, Image: image},
+				{Row: 3, Banner: &Banner{Title: "Third", Content: "Text"}},
+				{Row: 4, Banner: &Banner{Title: "Fourth", Content: "Text"}, Image: image},
+			}
+
+			report, err := svc.Import(context.Background(), rows, test.mode, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 1; i < len(report.Rows); i++ {
+				if report.Rows[i].ID <= report.Rows[i-1].ID {
+					t.Errorf("row %d has id %d after id %d of row %d", i+1, report.Rows[i].ID, report.Rows[i-1].ID, i)
+				}
+			}
+		})
+	}
+}
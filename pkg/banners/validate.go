@@ -0,0 +1,121 @@
+package banners
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Maximum lengths of banner fields (in characters)
+const (
+	MaxTitleLength   = 100
+	MaxContentLength = 1000
+	MaxButtonLength  = 50
+	MaxLinkLength    = 2048
+)
+
+// MaxWeight is largest weight of banner in weighted rotation
+const MaxWeight = 1000000
+
+// ImageExtensions is allowlist of image extensions
+var ImageExtensions = map[string]bool{
+	"png":  true,
+	"jpg":  true,
+	"jpeg": true,
+	"gif":  true,
+}
+
+// FieldError describes one invalid field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError contains all violations found in banner
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Error returns all violations in one line
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return "banner validation failed: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field string, message string) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: message})
+}
+
+// Validate checks banner and returns *ValidationError listing every violation
+func Validate(item *Banner) error {
+	verr := &ValidationError{}
+
+	checkLength(verr, "title", item.Title, MaxTitleLength, true)
+	checkLength(verr, "content", item.Content, MaxContentLength, true)
+	checkLength(verr, "button", item.Button, MaxButtonLength, false)
+	checkLength(verr, "link", item.Link, MaxLinkLength, false)
+
+	if item.Link != "" {
+		if !isAbsoluteHTTPURL(item.Link) {
+			verr.add("link", "must be absolute http(s) url")
+		}
+		if strings.TrimSpace(item.Button) == "" {
+			verr.add("button", "is required when link is set")
+		}
+	}
+
+	if item.Status != "" && !item.Status.Valid() {
+		verr.add("status", "must be one of draft, published, archived")
+	}
+	if !item.StartsAt.IsZero() && !item.EndsAt.IsZero() && !item.EndsAt.After(item.StartsAt) {
+		verr.add("ends_at", "must be after starts_at")
+	}
+
+	if item.Weight < 0 {
+		verr.add("weight", "must not be negative")
+	} else if item.Weight > MaxWeight {
+		verr.add("weight", "must be at most "+strconv.Itoa(MaxWeight))
+	}
+	for _, device := range item.Targeting.Devices {
+		if !device.Valid() {
+			verr.add("devices", "device "+strconv.Quote(string(device))+" is not supported")
+		}
+	}
+
+	if item.Image != "" {
+		ext := strings.ToLower(item.Image)
+		if i := strings.LastIndex(ext, "."); i >= 0 {
+			ext = ext[i+1:]
+		}
+		if !ImageExtensions[ext] {
+			verr.add("image", "extension "+strconv.Quote(ext)+" is not allowed")
+		}
+	}
+
+	if len(verr.Errors) != 0 {
+		return verr
+	}
+	return nil
+}
+
+func checkLength(verr *ValidationError, field string, value string, max int, required bool) {
+	if required && strings.TrimSpace(value) == "" {
+		verr.add(field, "is required")
+		return
+	}
+	if utf8.RuneCountInString(value) > max {
+		verr.add(field, "must be at most "+strconv.Itoa(max)+" characters")
+	}
+}
+
+func isAbsoluteHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,88 @@
+package banners
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	valid := func(change func(item *Banner)) *Banner {
+		item := &Banner{Title: "Sale", Content: "Everything half price", Status: StatusDraft}
+		if change != nil {
+			change(item)
+		}
+		return item
+	}
+
+	tests := []struct {
+		name   string
+		item   *Banner
+		fields []string
+	}{
+		{"valid", valid(nil), nil},
+		{"valid with link", valid(func(item *Banner) {
+			item.Link, item.Button = "https://example.com/sale", "Buy"
+		}), nil},
+		{"missing title and content", valid(func(item *Banner) {
+			item.Title, item.Content = " ", ""
+		}), []string{"title", "content"}},
+		{"too long title", valid(func(item *Banner) {
+			item.Title = strings.Repeat("ж", MaxTitleLength+1)
+		}), []string{"title"}},
+		{"title of max length in runes", valid(func(item *Banner) {
+			item.Title = strings.Repeat("ж", MaxTitleLength)
+		}), nil},
+		{"relative link without button", valid(func(item *Banner) {
+			item.Link = "/sale"
+		}), []string{"link", "button"}},
+		{"unknown status", valid(func(item *Banner) {
+			item.Status = "hidden"
+		}), []string{"status"}},
+		{"window ends before start", valid(func(item *Banner) {
+			item.StartsAt, item.EndsAt = now, now.Add(-time.Hour)
+		}), []string{"ends_at"}},
+		{"negative weight", valid(func(item *Banner) {
+			item.Weight = -1
+		}), []string{"weight"}},
+		{"weight too large", valid(func(item *Banner) {
+			item.Weight = MaxWeight + 1
+		}), []string{"weight"}},
+		{"max weight", valid(func(item *Banner) {
+			item.Weight = MaxWeight
+		}), nil},
+		{"unknown device", valid(func(item *Banner) {
+			item.Targeting.Devices = []Device{DeviceMobile, "watch"}
+		}), []string{"devices"}},
+		{"image extension", valid(func(item *Banner) {
+			item.Image = "1.exe"
+		}), []string{"image"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.item)
+			if test.fields == nil {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate() = %v, want *ValidationError", err)
+			}
+			var fields []string
+			for _, fe := range verr.Errors {
+				fields = append(fields, fe.Field)
+			}
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("invalid fields = %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package banners
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+
+	"github.com/MrHakimov/http/pkg/logging"
+)
+
+// VariantWidths are widths (in pixels) of generated responsive variants,
+// variants are made only for widths smaller than original image
+var VariantWidths = []int{320, 640, 1280}
+
+// BestVariant returns key of smallest image which is at least width pixels wide,
+// original image is returned when width is not positive or bigger than all variants
+func (b *Banner) BestVariant(width int) string {
+	if width <= 0 {
+		return b.Image
+	}
+
+	best, bestWidth := b.Image, b.Width
+	for w, key := range b.Variants {
+		if w >= width && (w < bestWidth || bestWidth < width) {
+			best, bestWidth = key, w
+		}
+	}
+	return best
+}
+
+// imageKeys returns keys of original image and all its variants
+func imageKeys(b *Banner) []string {
+	keys := make([]string, 0, len(b.Variants)+1)
+	if b.Image != "" {
+		keys = append(keys, b.Image)
+	}
+	for _, key := range b.Variants {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// uploadVariants resizes uploaded image to VariantWidths and stores results under keys
+// starting with prefix, variants stored before failure are returned together with error
+func (s *Service) uploadVariants(ctx context.Context, upload *uploadedImage, prefix string) (map[int]string, error) {
+	variants := make(map[int]string)
+
+	// gif is encoded as png, so variants of animated images keep only first frame
+	ext, mimeType := upload.ext, upload.mime
+	if ext == "gif" {
+		ext, mimeType = "png", "image/png"
+	}
+
+	for _, width := range VariantWidths {
+		if width >= upload.width {
+			continue
+		}
+		height := upload.height * width / upload.width
+		if height < 1 {
+			height = 1
+		}
+
+		var buf bytes.Buffer
+		err := encodeImage(&buf, resize(upload.img, width, height), ext)
+		if err != nil {
+			logging.FromContext(ctx).Error("encode image variant", "key", prefix, "width", width, "error", err)
+			return variants, errors.New("error encode image variant")
+		}
+
+		key := prefix + "-" + strconv.Itoa(width) + "." + ext
+		err = s.putImage(ctx, key, &buf, int64(buf.Len()), mimeType)
+		if err != nil {
+			logging.FromContext(ctx).Error("write image variant", "key", key, "error", err)
+			return variants, errors.New("error to write file")
+		}
+		variants[width] = key
+	}
+	return variants, nil
+}
+
+func encodeImage(buf *bytes.Buffer, img image.Image, ext string) error {
+	if ext == "jpg" {
+		return jpeg.Encode(buf, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(buf, img)
+}
+
+// resize scales src to width x height averaging all source pixels
+// covered by every destination pixel (box filter), which is good enough for downscaling
+func resize(src image.Image, width int, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := (y + 1) * srcH / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := (x + 1) * srcW / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(rgba.Pix[offset])
+					g += int(rgba.Pix[offset+1])
+					b += int(rgba.Pix[offset+2])
+					a += int(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
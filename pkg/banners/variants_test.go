@@ -0,0 +1,32 @@
+package banners
+
+import "testing"
+
+func TestBestVariant(t *testing.T) {
+	variants := map[int]string{320: "1-320.png", 640: "1-640.png", 1280: "1-1280.png"}
+
+	tests := []struct {
+		name   string
+		banner Banner
+		width  int
+		want   string
+	}{
+		{"no width", Banner{Image: "1.png", Width: 2000, Variants: variants}, 0, "1.png"},
+		{"smallest variant", Banner{Image: "1.png", Width: 2000, Variants: variants}, 100, "1-320.png"},
+		{"exact width", Banner{Image: "1.png", Width: 2000, Variants: variants}, 640, "1-640.png"},
+		{"next wider variant", Banner{Image: "1.png", Width: 2000, Variants: variants}, 641, "1-1280.png"},
+		{"wider than variants", Banner{Image: "1.png", Width: 2000, Variants: variants}, 1500, "1.png"},
+		{"wider than original", Banner{Image: "1.png", Width: 2000, Variants: variants}, 4000, "1.png"},
+		{"original narrower than variant", Banner{Image: "1.png", Width: 500, Variants: map[int]string{320: "1-320.png"}}, 400, "1.png"},
+		{"no variants", Banner{Image: "1.png", Width: 200}, 100, "1.png"},
+		{"no image", Banner{}, 100, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.banner.BestVariant(test.width); got != test.want {
+				t.Errorf("BestVariant(%d) = %q, want %q", test.width, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/server"
+)
+
+// Config is CORS policy
+type Config struct {
+	// AllowedOrigins are origins like https://admin.example.com,
+	// https://*.example.com for any subdomain or * for any origin
+	AllowedOrigins []string
+	// AllowedMethods are GET, HEAD and POST when empty
+	AllowedMethods []string
+	// AllowedHeaders are request headers client may send, * allows any
+	AllowedHeaders []string
+	// ExposedHeaders are response headers client may read
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge is how long preflight response may be cached
+	MaxAge time.Duration
+}
+
+// CORS answers preflight requests and adds CORS headers to responses
+type CORS struct {
+	config  Config
+	methods map[string]bool
+	headers map[string]bool
+}
+
+// New creates middleware of config
+func New(config Config) *CORS {
+	if len(config.AllowedMethods) == 0 {
+		config.AllowedMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	c := &CORS{config: config, methods: make(map[string]bool), headers: make(map[string]bool)}
+	for _, method := range config.AllowedMethods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range config.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	return c
+}
+
+// AllowsOrigin reports whether origin matches one of allowed origins
+func (c *CORS) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range c.config.AllowedOrigins {
+		if matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchOrigin compares origin with pattern, *. in host of pattern matches any subdomain
+func matchOrigin(pattern string, origin string) bool {
+	if pattern == "*" || strings.EqualFold(pattern, origin) {
+		return true
+	}
+	if !strings.Contains(pattern, "://*.") {
+		return false
+	}
+
+	patternURL, err := url.Parse(strings.Replace(pattern, "*.", "", 1))
+	if err != nil {
+		return false
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(patternURL.Scheme, originURL.Scheme) &&
+		patternURL.Port() == originURL.Port() &&
+		strings.HasSuffix(strings.ToLower(originURL.Hostname()), "."+strings.ToLower(patternURL.Hostname()))
+}
+
+// Headers returns CORS headers of response and whether request is preflight which must not reach handler;
+// headers are empty when origin or preflight request is not allowed
+func (c *CORS) Headers(method string, origin string, requestMethod string, requestHeaders string) (map[string]string, bool) {
+	preflight := method == http.MethodOptions && requestMethod != ""
+	headers := map[string]string{"Vary": "Origin"}
+	if preflight {
+		headers["Vary"] = "Origin, Access-Control-Request-Method, Access-Control-Request-Headers"
+	}
+	if !c.AllowsOrigin(origin) {
+		return headers, preflight
+	}
+
+	if preflight {
+		if !c.methods[strings.ToUpper(requestMethod)] {
+			return headers, true
+		}
+		for _, header := range strings.Split(requestHeaders, ",") {
+			header = strings.TrimSpace(header)
+			if header != "" && !c.headers["*"] && !c.headers[http.CanonicalHeaderKey(header)] {
+				return headers, true
+			}
+		}
+		headers["Access-Control-Allow-Methods"] = strings.Join(c.config.AllowedMethods, ", ")
+		if requestHeaders != "" {
+			headers["Access-Control-Allow-Headers"] = requestHeaders
+		}
+		if c.config.MaxAge > 0 {
+			headers["Access-Control-Max-Age"] = strconv.Itoa(int(c.config.MaxAge.Seconds()))
+		}
+	} else if len(c.config.ExposedHeaders) != 0 {
+		headers["Access-Control-Expose-Headers"] = strings.Join(c.config.ExposedHeaders, ", ")
+	}
+
+	// * can not be combined with credentials, so origin is echoed back then
+	headers["Access-Control-Allow-Origin"] = origin
+	if !c.config.AllowCredentials && len(c.config.AllowedOrigins) == 1 && c.config.AllowedOrigins[0] == "*" {
+		headers["Access-Control-Allow-Origin"] = "*"
+	}
+	if c.config.AllowCredentials {
+		headers["Access-Control-Allow-Credentials"] = "true"
+	}
+	return headers, preflight
+}
+
+// Handler adds CORS headers to responses of next and answers preflight requests with 204
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		headers, preflight := c.Headers(
+			request.Method,
+			request.Header.Get("Origin"),
+			request.Header.Get("Access-Control-Request-Method"),
+			request.Header.Get("Access-Control-Request-Headers"),
+		)
+		for name, value := range headers {
+			if name == "Vary" {
+				writer.Header().Add(name, value)
+				continue
+			}
+			writer.Header().Set(name, value)
+		}
+		if preflight {
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// HandlerFunc does the same as Handler for pkg/server, headers are put to ResponseHeaders
+func (c *CORS) HandlerFunc(next server.HandlerFunc) server.HandlerFunc {
+	return func(req *server.Request) {
+		headers, preflight := c.Headers(
+			req.Method,
+			req.Header("Origin"),
+			req.Header("Access-Control-Request-Method"),
+			req.Header("Access-Control-Request-Headers"),
+		)
+		if preflight {
+			if err := req.WriteResponse(http.StatusNoContent, headers, nil); err != nil {
+				logging.Default().Warn("write response", "error", err)
+			}
+			return
+		}
+		if req.ResponseHeaders == nil {
+			req.ResponseHeaders = make(map[string]string)
+		}
+		for name, value := range headers {
+			req.ResponseHeaders[name] = value
+		}
+		next(req)
+	}
+}
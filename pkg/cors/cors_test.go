@@ -0,0 +1,155 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		{"*", "https://any.example", true},
+		{"https://admin.example.com", "https://admin.example.com", true},
+		{"https://admin.example.com", "HTTPS://Admin.Example.com", true},
+		{"https://admin.example.com", "http://admin.example.com", false},
+		{"https://*.example.com", "https://admin.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://badexample.com", false},
+		{"https://*.example.com", "https://admin.example.com:8443", false},
+		{"https://*.example.com:8443", "https://admin.example.com:8443", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.origin, func(t *testing.T) {
+			if got := matchOrigin(test.pattern, test.origin); got != test.want {
+				t.Errorf("matchOrigin(%q, %q) = %v, want %v", test.pattern, test.origin, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	admin := Config{
+		AllowedOrigins: []string{"https://admin.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders: []string{"Content-Type", "If-Match"},
+		ExposedHeaders: []string{"ETag"},
+		MaxAge:         10 * time.Minute,
+	}
+	credentials := admin
+	credentials.AllowCredentials = true
+	anyOrigin := Config{AllowedOrigins: []string{"*"}}
+	anyWithCredentials := anyOrigin
+	anyWithCredentials.AllowCredentials = true
+
+	preflightVary := []string{"Origin, Access-Control-Request-Method, Access-Control-Request-Headers"}
+	tests := []struct {
+		name   string
+		config Config
+		method string
+		header http.Header
+		// reached is whether request gets to next handler, which answers 200 with X-Handler header
+		reached bool
+		status  int
+		want    http.Header
+	}{
+		{"preflight of allowed origin", admin, http.MethodOptions, http.Header{
+			"Origin":                         {"https://admin.example.com"},
+			"Access-Control-Request-Method":  {http.MethodPut},
+			"Access-Control-Request-Headers": {"content-type, if-match"},
+		}, false, http.StatusNoContent, http.Header{
+			"Vary":                         preflightVary,
+			"Access-Control-Allow-Origin":  {"https://admin.example.com"},
+			"Access-Control-Allow-Methods": {"GET, PUT"},
+			"Access-Control-Allow-Headers": {"content-type, if-match"},
+			"Access-Control-Max-Age":       {"600"},
+		}},
+		{"preflight of disallowed origin", admin, http.MethodOptions, http.Header{
+			"Origin":                        {"https://evil.example"},
+			"Access-Control-Request-Method": {http.MethodPut},
+		}, false, http.StatusNoContent, http.Header{"Vary": preflightVary}},
+		{"preflight of disallowed method", admin, http.MethodOptions, http.Header{
+			"Origin":                        {"https://admin.example.com"},
+			"Access-Control-Request-Method": {http.MethodDelete},
+		}, false, http.StatusNoContent, http.Header{"Vary": preflightVary}},
+		{"preflight of disallowed header", admin, http.MethodOptions, http.Header{
+			"Origin":                         {"https://admin.example.com"},
+			"Access-Control-Request-Method":  {http.MethodPut},
+			"Access-Control-Request-Headers": {"X-Secret"},
+		}, false, http.StatusNoContent, http.Header{"Vary": preflightVary}},
+		{"request of allowed origin", admin, http.MethodGet, http.Header{
+			"Origin": {"https://admin.example.com"},
+		}, true, http.StatusOK, http.Header{
+			"Vary":                          {"Origin"},
+			"Access-Control-Allow-Origin":   {"https://admin.example.com"},
+			"Access-Control-Expose-Headers": {"ETag"},
+		}},
+		{"request of disallowed origin", admin, http.MethodGet, http.Header{
+			"Origin": {"https://evil.example"},
+		}, true, http.StatusOK, http.Header{"Vary": {"Origin"}}},
+		{"credentials", credentials, http.MethodGet, http.Header{
+			"Origin": {"https://admin.example.com"},
+		}, true, http.StatusOK, http.Header{
+			"Vary":                             {"Origin"},
+			"Access-Control-Allow-Origin":      {"https://admin.example.com"},
+			"Access-Control-Allow-Credentials": {"true"},
+			"Access-Control-Expose-Headers":    {"ETag"},
+		}},
+		{"any origin", anyOrigin, http.MethodGet, http.Header{
+			"Origin": {"https://shop.example"},
+		}, true, http.StatusOK, http.Header{
+			"Vary":                        {"Origin"},
+			"Access-Control-Allow-Origin": {"*"},
+		}},
+		{"any origin with credentials echoes origin", anyWithCredentials, http.MethodGet, http.Header{
+			"Origin": {"https://shop.example"},
+		}, true, http.StatusOK, http.Header{
+			"Vary":                             {"Origin"},
+			"Access-Control-Allow-Origin":      {"https://shop.example"},
+			"Access-Control-Allow-Credentials": {"true"},
+		}},
+		{"not CORS request", admin, http.MethodGet, http.Header{}, true, http.StatusOK, http.Header{"Vary": {"Origin"}}},
+		{"OPTIONS which is not preflight", admin, http.MethodOptions, http.Header{
+			"Origin": {"https://admin.example.com"},
+		}, true, http.StatusOK, http.Header{
+			"Vary":                          {"Origin"},
+			"Access-Control-Allow-Origin":   {"https://admin.example.com"},
+			"Access-Control-Expose-Headers": {"ETag"},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reached := false
+			handler := New(test.config).Handler(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				reached = true
+				writer.Header().Set("X-Handler", "next")
+			}))
+			request := httptest.NewRequest(test.method, "/banners.getAll", nil)
+			request.Header = test.header
+			recorder := httptest.NewRecorder()
+
+			handler.ServeHTTP(recorder, request)
+			if reached != test.reached || recorder.Code != test.status {
+				t.Fatalf("handler reached = %v, status = %d, want %v, %d", reached, recorder.Code, test.reached, test.status)
+			}
+			header := recorder.Header()
+			if test.reached {
+				if header.Get("X-Handler") != "next" {
+					t.Errorf("headers of handler are lost: %v", header)
+				}
+				header.Del("X-Handler")
+			}
+			if !reflect.DeepEqual(header, test.want) {
+				t.Errorf("headers = %v, want %v", header, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// errDiskSpaceUnsupported is returned by freeSpace on platforms where it is not known
+var errDiskSpaceUnsupported = errors.New("disk space is not supported on this platform")
+
+// probeKey is blob looked up by Store check
+const probeKey = "health/probe"
+
+// Writable checks that file can be created in dir
+func Writable(dir string) Check {
+	return func(ctx context.Context) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		file, err := ioutil.TempFile(dir, ".probe-*")
+		if err != nil {
+			return err
+		}
+		name := file.Name()
+		_, err = file.Write([]byte("ok"))
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if rerr := os.Remove(name); err == nil {
+			err = rerr
+		}
+		return err
+	}
+}
+
+// Store checks that store answers reads, it only looks probe blob up, so readiness
+// checks neither write nor delete anything; missing probe blob is fine
+func Store(store storage.BlobStore) Check {
+	return func(ctx context.Context) error {
+		reader, err := store.Get(ctx, probeKey)
+		if err == storage.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return reader.Close()
+	}
+}
+
+// DiskSpace checks that file system of dir has at least minFree bytes available,
+// it passes on platforms where free space is not known
+func DiskSpace(dir string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		free, err := freeSpace(dir)
+		if err == errDiskSpaceUnsupported {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d bytes free, %d required", free, minFree)
+		}
+		return nil
+	}
+}
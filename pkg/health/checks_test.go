@@ -0,0 +1,72 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/storage"
+)
+
+// countingStore is memory store counting changes, Get fails with getErr when it is set
+type countingStore struct {
+	*storage.MemoryStore
+	getErr  error
+	changes int
+}
+
+func (s *countingStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *countingStore) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	s.changes++
+	return s.MemoryStore.Put(ctx, key, data, contentType)
+}
+
+func (s *countingStore) Delete(ctx context.Context, key string) error {
+	s.changes++
+	return s.MemoryStore.Delete(ctx, key)
+}
+
+func TestStore(t *testing.T) {
+	unavailable := errors.New("connection refused")
+
+	tests := []struct {
+		name   string
+		probe  bool
+		getErr error
+		err    error
+	}{
+		{"without probe blob", false, nil, nil},
+		{"with probe blob", true, nil, nil},
+		{"unavailable", false, unavailable, unavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			memory := storage.NewMemoryStore("")
+			if test.probe {
+				if err := memory.Put(context.Background(), probeKey, bytes.NewReader([]byte("ok")), "text/plain"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			store := &countingStore{MemoryStore: memory, getErr: test.getErr}
+			check := Store(store)
+
+			for i := 0; i < 3; i++ {
+				if err := check(context.Background()); err != test.err {
+					t.Fatalf("check() = %v, want %v", err, test.err)
+				}
+			}
+			if store.changes != 0 {
+				t.Errorf("check changed store %d times, want read-only check", store.changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package health
+
+import "syscall"
+
+// freeSpace returns bytes available to unprivileged user on file system of dir
+func freeSpace(dir string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package health
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// freeSpace returns bytes available to caller on volume of dir
+func freeSpace(dir string) (uint64, error) {
+	path, err := syscall.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var available, total, free uint64
+	ok, _, err := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(path)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&free)),
+	)
+	if ok == 0 {
+		return 0, err
+	}
+	return available, nil
+}
@@ -0,0 +1,175 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/server"
+)
+
+// Check returns error when dependency is not ready
+type Check func(ctx context.Context) error
+
+// CheckTimeout limits every readiness check
+const CheckTimeout = 2 * time.Second
+
+// Status of service or single check
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// Result is outcome of one check
+type Result struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is outcome of all readiness checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Registry keeps readiness checks by name
+type Registry struct {
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// NewRegistry creates registry without checks
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register adds check, check with same name is replaced
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	r.checks[name] = check
+	r.mu.Unlock()
+}
+
+// Run executes all checks concurrently, service is ready when every check passes
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := make(map[string]Check, len(r.checks))
+	for name, check := range r.checks {
+		checks[name] = check
+	}
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			started := time.Now()
+			err := check(checkCtx)
+			result := Result{Status: StatusOK, DurationMS: time.Since(started).Milliseconds()}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = StatusFail
+			}
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return report
+}
+
+// readiness returns status code and body of readiness response
+func (r *Registry) readiness(ctx context.Context) (int, []byte) {
+	report := r.Run(ctx)
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	return status, marshal(report)
+}
+
+func liveness() []byte {
+	return marshal(map[string]string{"status": StatusOK})
+}
+
+func marshal(value interface{}) []byte {
+	data, err := json.Marshal(value)
+	if err != nil {
+		logging.Default().Error("marshal health response", "error", err)
+		return []byte("{}")
+	}
+	return data
+}
+
+// HandleLiveness answers 200 while process is able to serve requests
+func HandleLiveness(writer http.ResponseWriter, request *http.Request) {
+	write(writer, request, http.StatusOK, liveness())
+}
+
+// HandleReadiness runs checks and answers 200 or 503 with report
+func (r *Registry) HandleReadiness(writer http.ResponseWriter, request *http.Request) {
+	status, body := r.readiness(request.Context())
+	write(writer, request, status, body)
+}
+
+// HandleVersion answers build information
+func HandleVersion(writer http.ResponseWriter, request *http.Request) {
+	write(writer, request, http.StatusOK, marshal(ReadBuildInfo()))
+}
+
+func write(writer http.ResponseWriter, request *http.Request, status int, body []byte) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(status)
+	if _, err := writer.Write(body); err != nil {
+		logging.FromContext(request.Context()).Warn("write response", "error", err)
+	}
+}
+
+// Handlers returns /healthz, /readyz and /version handlers for pkg/server
+func (r *Registry) Handlers() map[string]server.HandlerFunc {
+	return map[string]server.HandlerFunc{
+		"/healthz": func(req *server.Request) {
+			writeServer(req, http.StatusOK, liveness())
+		},
+		"/readyz": func(req *server.Request) {
+			status, body := r.readiness(req.Context())
+			writeServer(req, status, body)
+		},
+		"/version": func(req *server.Request) {
+			writeServer(req, http.StatusOK, marshal(ReadBuildInfo()))
+		},
+	}
+}
+
+// RegisterServer registers Handlers in srv
+func (r *Registry) RegisterServer(srv *server.Server) {
+	for path, handler := range r.Handlers() {
+		srv.Register(path, handler)
+	}
+}
+
+func writeServer(req *server.Request, status int, body []byte) {
+	err := req.WriteResponse(status, map[string]string{
+		"Content-Type":  "application/json",
+		"Cache-Control": "no-store",
+	}, body)
+	if err != nil {
+		logging.Default().Warn("write response", "error", err)
+	}
+}
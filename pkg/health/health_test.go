@@ -0,0 +1,69 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/server"
+)
+
+// discardConn is connection of pkg/server request remembering written response
+type discardConn struct {
+	net.Conn
+	written []byte
+}
+
+func (c *discardConn) Write(data []byte) (int, error) {
+	c.written = append(c.written, data...)
+	return len(data), nil
+}
+
+func TestReadinessUsesRequestContext(t *testing.T) {
+	tests := []struct {
+		name string
+		// serve runs readiness handler with ctx and returns status of response
+		serve func(registry *Registry, ctx context.Context) int
+	}{
+		{"net/http", func(registry *Registry, ctx context.Context) int {
+			recorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/readyz", nil).WithContext(ctx)
+			registry.HandleReadiness(recorder, request)
+			return recorder.Code
+		}},
+		{"pkg/server", func(registry *Registry, ctx context.Context) int {
+			conn := &discardConn{}
+			req := (&server.Request{Conn: conn, Method: http.MethodGet, Path: "/readyz"}).WithContext(ctx)
+			registry.Handlers()["/readyz"](req)
+			return req.Status()
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var deadline time.Time
+			registry := NewRegistry()
+			registry.Register("context", func(ctx context.Context) error {
+				deadline, _ = ctx.Deadline()
+				return ctx.Err()
+			})
+
+			started := time.Now()
+			if status := test.serve(registry, context.Background()); status != http.StatusOK {
+				t.Errorf("status = %d, want %d", status, http.StatusOK)
+			}
+			if deadline.IsZero() || deadline.After(started.Add(CheckTimeout+time.Second)) {
+				t.Errorf("check deadline = %v, want at most %v after start", deadline, CheckTimeout)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if status := test.serve(registry, ctx); status != http.StatusServiceUnavailable {
+				t.Errorf("status of canceled request = %d, want %d", status, http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
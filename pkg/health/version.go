@@ -0,0 +1,52 @@
+package health
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build variables are set by linker, e.g.
+// go build -ldflags "-X github.com/MrHakimov/http/pkg/health.Commit=$(git rev-parse HEAD)"
+var (
+	Version   = ""
+	Commit    = ""
+	BuildTime = ""
+)
+
+// BuildInfo describes running binary
+type BuildInfo struct {
+	Module    string `json:"module"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// ReadBuildInfo returns module version from binary and VCS info given by linker or,
+// when it is not given, stamped into binary by go build (Go 1.18 and newer);
+// Version variable overrides module version which is (devel) for local builds
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   "(devel)",
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		info.Module = build.Main.Path
+		if build.Main.Version != "" {
+			info.Version = build.Main.Version
+		}
+		revision, at := vcsInfo(build)
+		if info.Commit == "" {
+			info.Commit = revision
+		}
+		if info.BuildTime == "" {
+			info.BuildTime = at
+		}
+	}
+	if Version != "" {
+		info.Version = Version
+	}
+	return info
+}
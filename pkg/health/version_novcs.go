@@ -0,0 +1,11 @@
+//go:build !go1.18
+// +build !go1.18
+
+package health
+
+import "runtime/debug"
+
+// vcsInfo returns nothing, go build stamps VCS info into binary since Go 1.18
+func vcsInfo(build *debug.BuildInfo) (revision string, at string) {
+	return "", ""
+}
@@ -0,0 +1,19 @@
+//go:build go1.18
+// +build go1.18
+
+package health
+
+import "runtime/debug"
+
+// vcsInfo returns revision and commit time stamped into binary by go build
+func vcsInfo(build *debug.BuildInfo) (revision string, at string) {
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.time":
+			at = setting.Value
+		}
+	}
+	return revision, at
+}
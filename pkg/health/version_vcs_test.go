@@ -0,0 +1,48 @@
+//go:build go1.18
+// +build go1.18
+
+package health
+
+import (
+	"runtime/debug"
+	"testing"
+)
+
+func TestVCSInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []debug.BuildSetting
+		revision string
+		at       string
+	}{
+		{"stamped", []debug.BuildSetting{
+			{Key: "-compiler", Value: "gc"},
+			{Key: "vcs", Value: "git"},
+			{Key: "vcs.revision", Value: "0123abc"},
+			{Key: "vcs.time", Value: "2026-01-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "false"},
+		}, "0123abc", "2026-01-01T12:00:00Z"},
+		{"built without vcs", []debug.BuildSetting{{Key: "-compiler", Value: "gc"}}, "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			revision, at := vcsInfo(&debug.BuildInfo{Settings: test.settings})
+			if revision != test.revision || at != test.at {
+				t.Errorf("vcsInfo() = %q, %q, want %q, %q", revision, at, test.revision, test.at)
+			}
+		})
+	}
+}
+
+func TestReadBuildInfoPrefersLinkerVariables(t *testing.T) {
+	defer func(version, commit, buildTime string) {
+		Version, Commit, BuildTime = version, commit, buildTime
+	}(Version, Commit, BuildTime)
+	Version, Commit, BuildTime = "v1.2.3", "feedbee", "2026-02-03T04:05:06Z"
+
+	info := ReadBuildInfo()
+	if info.Version != Version || info.Commit != Commit || info.BuildTime != BuildTime {
+		t.Errorf("ReadBuildInfo() = %+v, want linker variables", info)
+	}
+}
@@ -0,0 +1,256 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Level of log entry, entries below level of logger are dropped
+type Level int
+
+// Supported levels
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(name string) (Level, bool) {
+	for i, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(i), true
+		}
+	}
+	return LevelInfo, false
+}
+
+// Format of log lines
+type Format int
+
+// Supported formats
+const (
+	FormatLogfmt Format = iota
+	FormatJSON
+)
+
+// ParseFormat parses logfmt or json
+func ParseFormat(name string) (Format, bool) {
+	switch strings.ToLower(name) {
+	case "logfmt":
+		return FormatLogfmt, true
+	case "json":
+		return FormatJSON, true
+	}
+	return FormatLogfmt, false
+}
+
+// output is shared by logger and loggers derived by With, so lines are not interleaved
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Logger writes one line of time, level, message and key/value fields per entry
+type Logger struct {
+	out    *output
+	level  Level
+	format Format
+	fields []interface{}
+}
+
+// New creates logger writing entries of level and above to w
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{out: &output{w: w}, level: level, format: format}
+}
+
+// With returns logger adding keyvals to every entry, keyvals are pairs of string key and value
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{out: l.out, level: l.level, format: l.format, fields: fields}
+}
+
+// Enabled reports whether entries of level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug writes entry of debug level
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+// Info writes entry of info level
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+// Warn writes entry of warn level
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+// Error writes entry of error level
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := make([]interface{}, 0, 6+len(l.fields)+len(keyvals))
+	entry = append(entry, "time", time.Now().UTC().Format("2006-01-02T15:04:05.000Z07:00"), "level", level.String(), "msg", msg)
+	entry = append(entry, l.fields...)
+	entry = append(entry, keyvals...)
+	if len(entry)%2 != 0 {
+		entry = append(entry, nil)
+	}
+
+	var line []byte
+	if l.format == FormatJSON {
+		line = encodeJSON(entry)
+	} else {
+		line = encodeLogfmt(entry)
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_, _ = l.out.w.Write(line)
+}
+
+// value converts errors, durations and stringers to text, other values are kept as is
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+func encodeJSON(entry []interface{}) []byte {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(entry); i += 2 {
+		if i != 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(entry[i]))
+		b.Write(key)
+		b.WriteByte(':')
+		data, err := json.Marshal(value(entry[i+1]))
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(entry[i+1]))
+		}
+		b.Write(data)
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func encodeLogfmt(entry []interface{}) []byte {
+	var b strings.Builder
+	for i := 0; i < len(entry); i += 2 {
+		if i != 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(logfmtKey(fmt.Sprint(entry[i])))
+		b.WriteByte('=')
+		v := value(entry[i+1])
+		if v == nil {
+			continue
+		}
+		b.WriteString(logfmtValue(fmt.Sprint(v)))
+	}
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// logfmtKey replaces characters not allowed in keys with underscores
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || unicode.IsSpace(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue quotes empty values and values with spaces, quotes, equal signs or control characters
+func logfmtValue(v string) string {
+	if v == "" {
+		return `""`
+	}
+	for _, r := range v {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f || unicode.IsSpace(r) {
+			return strconv.Quote(v)
+		}
+	}
+	return v
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stderr, LevelInfo, FormatLogfmt)
+)
+
+// Default returns logger used when context carries none, it writes info and above as logfmt to stderr
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces logger returned by Default
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defaultLogger = l
+	defaultMu.Unlock()
+}
+
+type contextKey struct{}
+
+// WithContext returns copy of ctx carrying l
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns logger of ctx or Default when ctx carries none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return Default()
+}
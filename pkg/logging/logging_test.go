@@ -0,0 +1,196 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogfmtValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"", `""`},
+		{"two words", `"two words"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{"a=b", `"a=b"`},
+		{"tab\there", `"tab\there"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"bell\a", `"bell\a"`},
+		{"del\x7f", `"del\x7f"`},
+		{"no\u00a0break", `"no\u00a0break"`},
+		{"/banners.getAll?id=1", `"/banners.getAll?id=1"`},
+		{"unicode-ß", "unicode-ß"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if got := logfmtValue(test.value); got != test.want {
+				t.Errorf("logfmtValue(%q) = %s, want %s", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLogfmtKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"banner_id", "banner_id"},
+		{"", "_"},
+		{"two words", "two_words"},
+		{`a="b"`, "a__b_"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			if got := logfmtKey(test.key); got != test.want {
+				t.Errorf("logfmtKey(%q) = %q, want %q", test.key, got, test.want)
+			}
+		})
+	}
+}
+
+// timeField matches time of entry, which is removed before comparison
+var timeField = regexp.MustCompile(`^time=\S+ |"time":"[^"]+",`)
+
+func TestFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyvals []interface{}
+		logfmt  string
+		json    string
+	}{
+		{"values", []interface{}{"id", 7, "ok", true, "took", 1500 * time.Millisecond, "error", errors.New("not found")},
+			`level=info msg="banner saved" id=7 ok=true took=1.5s error="not found"`,
+			`{"level":"info","msg":"banner saved","id":7,"ok":true,"took":"1.5s","error":"not found"}`},
+		{"odd keyvals", []interface{}{"id", 7, "dangling"},
+			`level=info msg="banner saved" id=7 dangling=`,
+			`{"level":"info","msg":"banner saved","id":7,"dangling":null}`},
+		{"nil value", []interface{}{"error", nil},
+			`level=info msg="banner saved" error=`,
+			`{"level":"info","msg":"banner saved","error":null}`},
+		{"unmarshallable values", []interface{}{"ratio", math.NaN(), "done", make(chan int)},
+			`level=info msg="banner saved" ratio=NaN done=0x`,
+			`{"level":"info","msg":"banner saved","ratio":"NaN","done":"0x`},
+		{"key which is not string", []interface{}{1, "one"},
+			`level=info msg="banner saved" 1=one`,
+			`{"level":"info","msg":"banner saved","1":"one"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, format := range []struct {
+				format Format
+				want   string
+			}{{FormatLogfmt, test.logfmt}, {FormatJSON, test.json}} {
+				var buf bytes.Buffer
+				New(&buf, LevelInfo, format.format).Info("banner saved", test.keyvals...)
+				line := buf.String()
+				if !strings.HasSuffix(line, "\n") {
+					t.Errorf("line %q does not end with newline", line)
+				}
+				if format.format == FormatJSON {
+					var decoded map[string]interface{}
+					if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+						t.Errorf("line %q is not JSON: %v", line, err)
+					}
+				}
+				// pointers of channels differ, so only their prefix is compared
+				if got := timeField.ReplaceAllString(strings.TrimSuffix(line, "\n"), ""); !strings.HasPrefix(got, format.want) {
+					t.Errorf("line = %s, want %s", got, format.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  []string
+	}{
+		{LevelDebug, []string{"debug", "info", "warn", "error"}},
+		{LevelInfo, []string{"info", "warn", "error"}},
+		{LevelWarn, []string{"warn", "error"}},
+		{LevelError, []string{"error"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.level.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := New(&buf, test.level, FormatLogfmt)
+			logger.Debug("entry")
+			logger.Info("entry")
+			logger.Warn("entry")
+			logger.Error("entry")
+
+			var levels []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				fields := strings.Fields(line)
+				levels = append(levels, strings.TrimPrefix(fields[1], "level="))
+			}
+			if strings.Join(levels, ",") != strings.Join(test.want, ",") {
+				t.Errorf("written levels = %v, want %v", levels, test.want)
+			}
+		})
+	}
+}
+
+func TestWith(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, LevelInfo, FormatLogfmt)
+	ctx := WithContext(context.Background(), logger.With("request_id", "abc"))
+
+	FromContext(ctx).With("user", "admin").Info("saved", "id", 1)
+	logger.Info("plain")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"level=info msg=saved request_id=abc user=admin id=1",
+		"level=info msg=plain",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("lines = %q, want %d", lines, len(want))
+	}
+	for i, line := range lines {
+		if got := timeField.ReplaceAllString(line, ""); got != want[i] {
+			t.Errorf("line %d = %s, want %s", i+1, got, want[i])
+		}
+	}
+	if FromContext(context.Background()) != Default() {
+		t.Error("FromContext() of context without logger is not Default()")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  Level
+		format Format
+		ok     bool
+	}{
+		{"debug", LevelDebug, FormatLogfmt, true},
+		{"WARN", LevelWarn, FormatLogfmt, true},
+		{"json", LevelInfo, FormatJSON, true},
+		{"verbose", LevelInfo, FormatLogfmt, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			level, levelOK := ParseLevel(test.name)
+			format, formatOK := ParseFormat(test.name)
+			if level != test.level || format != test.format || (levelOK || formatOK) != test.ok {
+				t.Errorf("ParseLevel(), ParseFormat() = %v, %v, %v, %v", level, levelOK, format, formatOK)
+			}
+		})
+	}
+}
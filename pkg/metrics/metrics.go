@@ -0,0 +1,265 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/MrHakimov/http/pkg/logging"
+)
+
+// ContentType is media type of Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency buckets in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// SizeBuckets are byte size buckets from 1 KiB to 16 MiB
+var SizeBuckets = []float64{1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20, 4 << 20, 16 << 20}
+
+// Registry keeps metrics and writes them in Prometheus text format
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// NewRegistry creates empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// counts are per bucket (not cumulative) for histograms, last one is +Inf
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// register returns family by name, registering same name with other kind or labels panics
+func (r *Registry) register(name string, help string, kind string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.families[name]; ok {
+		if existing.kind != kind || strings.Join(existing.labels, ",") != strings.Join(labels, ",") {
+			panic("metrics: " + name + " registered twice with different kind or labels")
+		}
+		return existing
+	}
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.families[name] = f
+	return f
+}
+
+// with returns series of label values, f.mu must be held
+func (f *family) with(labelValues []string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter only goes up
+type Counter struct {
+	family *family
+}
+
+// NewCounter registers counter with given label names
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{family: r.register(name, help, "counter", nil, labels)}
+}
+
+// Inc adds 1 to series of label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds non-negative value to series of label values
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic("metrics: counter " + c.family.name + " can not decrease")
+	}
+	c.family.mu.Lock()
+	c.family.with(labelValues).value += value
+	c.family.mu.Unlock()
+}
+
+// Gauge goes up and down
+type Gauge struct {
+	family *family
+}
+
+// NewGauge registers gauge with given label names
+func (r *Registry) NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{family: r.register(name, help, "gauge", nil, labels)}
+}
+
+// Set sets value of series of label values
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.family.mu.Lock()
+	g.family.with(labelValues).value = value
+	g.family.mu.Unlock()
+}
+
+// Add adds value, which may be negative, to series of label values
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	g.family.mu.Lock()
+	g.family.with(labelValues).value += value
+	g.family.mu.Unlock()
+}
+
+// Inc adds 1
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts 1
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	family *family
+}
+
+// NewHistogram registers histogram with sorted upper bounds of buckets, +Inf is added implicitly
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{family: r.register(name, help, "histogram", sorted, labels)}
+}
+
+// Observe adds value to series of label values
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.family.mu.Lock()
+	defer h.family.mu.Unlock()
+	s := h.family.with(labelValues)
+	i := sort.SearchFloat64s(h.family.buckets, value)
+	s.counts[i]++
+	s.sum += value
+	s.count++
+}
+
+// Write writes all metrics in text exposition format, families and series are sorted
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	buf := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(buf)
+	}
+	return buf.Flush()
+}
+
+func (f *family) write(buf *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	buf.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			buf.WriteString(f.name + labelPairs(f.labels, s.labelValues, "", "") + " " + formatValue(s.value) + "\n")
+			continue
+		}
+
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			buf.WriteString(f.name + "_bucket" + labelPairs(f.labels, s.labelValues, "le", formatValue(upper)) +
+				" " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		buf.WriteString(f.name + "_bucket" + labelPairs(f.labels, s.labelValues, "le", "+Inf") +
+			" " + strconv.FormatUint(s.count, 10) + "\n")
+		buf.WriteString(f.name + "_sum" + labelPairs(f.labels, s.labelValues, "", "") + " " + formatValue(s.sum) + "\n")
+		buf.WriteString(f.name + "_count" + labelPairs(f.labels, s.labelValues, "", "") + " " + strconv.FormatUint(s.count, 10) + "\n")
+	}
+}
+
+// labelPairs formats {name="value",...}, extra pair is appended when extraName is set
+func labelPairs(names []string, values []string, extraName string, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, name+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+extraValue+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func escapeHelp(value string) string {
+	return helpEscaper.Replace(value)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Handler serves metrics of registry
+func (r *Registry) Handler(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", ContentType)
+	if err := r.Write(writer); err != nil {
+		logging.FromContext(request.Context()).Warn("write response", "error", err)
+	}
+}
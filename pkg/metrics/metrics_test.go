@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name string
+		// record registers metrics in registry and records values
+		record func(registry *Registry)
+		want   string
+	}{
+		{"counter", func(registry *Registry) {
+			requests := registry.NewCounter("requests_total", "Requests handled.", "method", "status")
+			requests.Inc("POST", "200")
+			requests.Add(2, "GET", "200")
+		}, `# HELP requests_total Requests handled.
+# TYPE requests_total counter
+requests_total{method="GET",status="200"} 2
+requests_total{method="POST",status="200"} 1
+`},
+		{"gauge without labels", func(registry *Registry) {
+			connections := registry.NewGauge("connections", "Open connections.")
+			connections.Inc()
+			connections.Inc()
+			connections.Dec()
+		}, `# HELP connections Open connections.
+# TYPE connections gauge
+connections 1
+`},
+		{"histogram", func(registry *Registry) {
+			duration := registry.NewHistogram("duration_seconds", "Time spent.", []float64{1, 0.5}, "route")
+			for _, value := range []float64{0.25, 0.5, 0.75, 3} {
+				duration.Observe(value, "/banners")
+			}
+		}, `# HELP duration_seconds Time spent.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/banners",le="0.5"} 2
+duration_seconds_bucket{route="/banners",le="1"} 3
+duration_seconds_bucket{route="/banners",le="+Inf"} 4
+duration_seconds_sum{route="/banners"} 4.5
+duration_seconds_count{route="/banners"} 4
+`},
+		{"escaping", func(registry *Registry) {
+			errors := registry.NewCounter("errors_total", "Errors by \\ message\nof failure.", "message")
+			errors.Inc(`say "hi" \ bye` + "\nnow")
+		}, `# HELP errors_total Errors by \\ message\nof failure.
+# TYPE errors_total counter
+errors_total{message="say \"hi\" \\ bye\nnow"} 1
+`},
+		{"special values", func(registry *Registry) {
+			values := registry.NewGauge("values", "Special values.", "kind")
+			values.Set(math.Inf(1), "positive")
+			values.Set(math.Inf(-1), "negative")
+			values.Set(math.NaN(), "nan")
+		}, `# HELP values Special values.
+# TYPE values gauge
+values{kind="nan"} NaN
+values{kind="negative"} -Inf
+values{kind="positive"} +Inf
+`},
+		{"families are sorted", func(registry *Registry) {
+			registry.NewGauge("b", "B.").Set(2)
+			registry.NewGauge("a", "A.").Set(1)
+		}, `# HELP a A.
+# TYPE a gauge
+a 1
+# HELP b B.
+# TYPE b gauge
+b 2
+`},
+		{"family without series", func(registry *Registry) {
+			registry.NewCounter("idle_total", "Nothing yet.", "kind")
+		}, `# HELP idle_total Nothing yet.
+# TYPE idle_total counter
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := NewRegistry()
+			test.record(registry)
+			var buf bytes.Buffer
+			if err := registry.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != test.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	tests := []struct {
+		name     string
+		register func(registry *Registry)
+	}{
+		{"other kind", func(registry *Registry) {
+			registry.NewGauge("requests_total", "Requests.", "method")
+		}},
+		{"other labels", func(registry *Registry) {
+			registry.NewCounter("requests_total", "Requests.", "route")
+		}},
+		{"wrong count of label values", func(registry *Registry) {
+			registry.NewCounter("requests_total", "Requests.", "method").Inc("GET", "200")
+		}},
+		{"decreasing counter", func(registry *Registry) {
+			registry.NewCounter("requests_total", "Requests.", "method").Add(-1, "GET")
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			registry := NewRegistry()
+			registry.NewCounter("requests_total", "Requests.", "method")
+			defer func() {
+				if recover() == nil {
+					t.Error("no panic")
+				}
+			}()
+			test.register(registry)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewCounter("requests_total", "Requests.").Inc()
+	recorder := httptest.NewRecorder()
+
+	registry.Handler(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if contentType := recorder.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, ContentType)
+	}
+	if body := recorder.Body.String(); !strings.HasSuffix(body, "requests_total 1\n") {
+		t.Errorf("body = %q", body)
+	}
+}
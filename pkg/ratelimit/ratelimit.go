@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/auth"
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/server"
+)
+
+// Limit is token bucket refilled by Rate tokens per second up to Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// wait returns time needed to refill given number of tokens
+func (l Limit) wait(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if l.Rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / l.Rate * float64(time.Second))
+}
+
+// Result is outcome of taking token
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is time until bucket is full again
+	Reset time.Duration
+	// RetryAfter is time until next token when request is not allowed
+	RetryAfter time.Duration
+}
+
+// Client describes request for KeyFunc
+type Client struct {
+	IP string
+	// Principal is name of authenticated caller, empty for missing or invalid credentials
+	Principal string
+	Route     string
+}
+
+// KeyFunc selects bucket of client
+type KeyFunc func(client Client) string
+
+// ByIP gives every client IP its own bucket
+func ByIP(client Client) string {
+	return "ip:" + client.IP
+}
+
+// ByPrincipal gives every authenticated caller its own bucket, anonymous clients are limited by IP,
+// so rotating unknown keys does not give new buckets
+func ByPrincipal(client Client) string {
+	if client.Principal != "" {
+		return "principal:" + client.Principal
+	}
+	return ByIP(client)
+}
+
+// ByRoute shares one bucket among all clients of route
+func ByRoute(client Client) string {
+	return "route:" + client.Route
+}
+
+// Limiter allows requests while bucket of their key has tokens
+type Limiter struct {
+	store Store
+	limit Limit
+	key   KeyFunc
+	// TrustedProxies is number of proxies in front of server appending to X-Forwarded-For,
+	// client IP is address added by outermost of them; 0 means remote address is client IP
+	TrustedProxies int
+	// Authenticator identifies Principal of client, it is needed by ByPrincipal only
+	Authenticator auth.Authenticator
+	now           func() time.Time
+}
+
+// NewLimiter creates limiter, nil store means new MemoryStore and nil key means ByIP
+func NewLimiter(store Store, limit Limit, key KeyFunc) *Limiter {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	if key == nil {
+		key = ByIP
+	}
+	return &Limiter{store: store, limit: limit, key: key, now: time.Now}
+}
+
+// Allow takes token for client
+func (l *Limiter) Allow(client Client) Result {
+	return l.store.Take(l.key(client), l.limit, l.now())
+}
+
+// Headers returns RateLimit-* headers of result and Retry-After when request is not allowed
+func Headers(result Result) map[string]string {
+	headers := map[string]string{
+		"RateLimit-Limit":     strconv.Itoa(result.Limit),
+		"RateLimit-Remaining": strconv.Itoa(result.Remaining),
+		"RateLimit-Reset":     strconv.FormatInt(seconds(result.Reset), 10),
+	}
+	if !result.Allowed {
+		headers["Retry-After"] = strconv.FormatInt(seconds(result.RetryAfter), 10)
+	}
+	return headers
+}
+
+// seconds rounds duration up to whole seconds
+func seconds(duration time.Duration) int64 {
+	return int64(math.Ceil(duration.Seconds()))
+}
+
+// Handler limits requests to next, rejected ones get 429
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		result := l.Allow(Client{
+			IP:        l.clientIP(request.RemoteAddr, strings.Join(request.Header["X-Forwarded-For"], ",")),
+			Principal: l.principal(request),
+			Route:     request.URL.Path,
+		})
+		for name, value := range Headers(result) {
+			writer.Header().Set(name, value)
+		}
+		if !result.Allowed {
+			http.Error(writer, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// HandlerFunc limits requests to next handler of pkg/server, rejected ones get 429.
+// Headers of allowed requests are put to ResponseHeaders.
+func (l *Limiter) HandlerFunc(next server.HandlerFunc) server.HandlerFunc {
+	return func(req *server.Request) {
+		header := make(http.Header, len(req.Headers))
+		for name, value := range req.Headers {
+			header.Set(name, value)
+		}
+		result := l.Allow(Client{
+			IP:        l.clientIP(req.RemoteIP(), req.Header("X-Forwarded-For")),
+			Principal: l.principal(&http.Request{Header: header}),
+			Route:     req.Path,
+		})
+		headers := Headers(result)
+		if !result.Allowed {
+			body := []byte(http.StatusText(http.StatusTooManyRequests) + "\n")
+			if err := req.WriteResponse(http.StatusTooManyRequests, headers, body); err != nil {
+				logging.Default().Warn("write response", "error", err)
+			}
+			return
+		}
+		if req.ResponseHeaders == nil {
+			req.ResponseHeaders = make(map[string]string)
+		}
+		for name, value := range headers {
+			req.ResponseHeaders[name] = value
+		}
+		next(req)
+	}
+}
+
+// principal returns name of caller authenticated by request, empty when credentials are missing or invalid
+func (l *Limiter) principal(request *http.Request) string {
+	if l.Authenticator == nil {
+		return ""
+	}
+	principal, err := l.Authenticator.Authenticate(request)
+	if err != nil || principal == nil {
+		return ""
+	}
+	return principal.Name
+}
+
+// clientIP returns address which outermost trusted proxy puts to X-Forwarded-For, addresses left of it
+// may be forged by client; host of remote address is returned when no proxy is trusted or header is empty
+func (l *Limiter) clientIP(remoteAddr string, forwarded string) string {
+	if l.TrustedProxies > 0 && forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		i := len(hops) - l.TrustedProxies
+		if i < 0 {
+			i = 0
+		}
+		if hop := strings.TrimSpace(hops[i]); hop != "" {
+			return hop
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
@@ -0,0 +1,203 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/auth"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	limit := Limit{Rate: 2, Burst: 3}
+
+	tests := []struct {
+		name string
+		// takes are times of taking tokens since start
+		takes []time.Duration
+		// allowed are outcomes of takes
+		allowed    []bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{"burst", []time.Duration{0, 0, 0}, []bool{true, true, true}, 0, 0},
+		{"over burst", []time.Duration{0, 0, 0, 0}, []bool{true, true, true, false}, 0, 500 * time.Millisecond},
+		{"partly refilled", []time.Duration{0, 0, 0, 250 * time.Millisecond}, []bool{true, true, true, false}, 0, 250 * time.Millisecond},
+		{"refilled token", []time.Duration{0, 0, 0, 500 * time.Millisecond}, []bool{true, true, true, true}, 0, 0},
+		{"refill stops at burst", []time.Duration{0, time.Hour, time.Hour, time.Hour}, []bool{true, true, true, true}, 0, 0},
+		{"refill keeps remaining", []time.Duration{0, 0, time.Second}, []bool{true, true, true}, 2, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			var result Result
+			for i, take := range test.takes {
+				result = store.Take("key", limit, start.Add(take))
+				if result.Allowed != test.allowed[i] {
+					t.Fatalf("take %d allowed = %v, want %v", i+1, result.Allowed, test.allowed[i])
+				}
+			}
+			if result.Remaining != test.remaining || result.RetryAfter != test.retryAfter || result.Limit != limit.Burst {
+				t.Errorf("last result = %+v, want %d remaining of %d and retry after %v",
+					result, test.remaining, limit.Burst, test.retryAfter)
+			}
+		})
+	}
+}
+
+func TestHandlerRejects(t *testing.T) {
+	now := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	limiter := NewLimiter(nil, Limit{Rate: 0.5, Burst: 2}, nil)
+	limiter.now = func() time.Time { return now }
+	handler := limiter.Handler(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name string
+		// wait is time passed since previous request
+		wait       time.Duration
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{"first", 0, http.StatusNoContent, "1", ""},
+		{"second", 0, http.StatusNoContent, "0", ""},
+		{"over burst", 0, http.StatusTooManyRequests, "0", "2"},
+		{"still empty", time.Second, http.StatusTooManyRequests, "0", "1"},
+		{"refilled", time.Second, http.StatusNoContent, "0", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now = now.Add(test.wait)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, test.status)
+			}
+			header := recorder.Header()
+			if header.Get("RateLimit-Limit") != "2" || header.Get("RateLimit-Remaining") != test.remaining {
+				t.Errorf("RateLimit-Limit = %q, RateLimit-Remaining = %q, want 2, %s",
+					header.Get("RateLimit-Limit"), header.Get("RateLimit-Remaining"), test.remaining)
+			}
+			if got := header.Get("Retry-After"); got != test.retryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, test.retryAfter)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxies   int
+		forwarded []string
+		want      string
+	}{
+		{"no proxy", 0, nil, "10.0.0.1"},
+		{"forwarded is not trusted", 0, []string{"1.1.1.1"}, "10.0.0.1"},
+		{"one proxy", 1, []string{"1.1.1.1"}, "1.1.1.1"},
+		{"forged by client", 1, []string{"6.6.6.6, 1.1.1.1"}, "1.1.1.1"},
+		{"two proxies", 2, []string{"6.6.6.6, 1.1.1.1, 2.2.2.2"}, "1.1.1.1"},
+		{"fewer hops than proxies", 3, []string{"1.1.1.1, 2.2.2.2"}, "1.1.1.1"},
+		{"several headers", 1, []string{"6.6.6.6", "1.1.1.1"}, "1.1.1.1"},
+		{"spaces", 1, []string{" 1.1.1.1 "}, "1.1.1.1"},
+		{"empty hop", 1, []string{"1.1.1.1,"}, "10.0.0.1"},
+		{"no header behind proxy", 1, nil, "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var key string
+			limiter := NewLimiter(nil, Limit{Rate: 1, Burst: 1}, func(client Client) string {
+				key = client.IP
+				return ByIP(client)
+			})
+			limiter.TrustedProxies = test.proxies
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = "10.0.0.1:4000"
+			for _, value := range test.forwarded {
+				request.Header.Add("X-Forwarded-For", value)
+			}
+
+			limiter.Handler(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), request)
+			if key != test.want {
+				t.Errorf("client IP = %q, want %q", key, test.want)
+			}
+		})
+	}
+}
+
+func TestByPrincipal(t *testing.T) {
+	tests := []struct {
+		name string
+		// keys are X-API-Key of requests made from the same IP
+		keys   []string
+		status []int
+	}{
+		{"known key", []string{"key-1", "key-1"}, []int{http.StatusNoContent, http.StatusTooManyRequests}},
+		{"keys of one principal", []string{"key-1", "key-2"}, []int{http.StatusNoContent, http.StatusTooManyRequests}},
+		{"keys of other principals", []string{"key-1", "key-3"}, []int{http.StatusNoContent, http.StatusNoContent}},
+		{"rotated unknown keys", []string{"guess-1", "guess-2", "guess-3"},
+			[]int{http.StatusNoContent, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		{"unknown key and no key", []string{"guess-1", ""}, []int{http.StatusNoContent, http.StatusTooManyRequests}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := NewLimiter(nil, Limit{Rate: 0.001, Burst: 1}, ByPrincipal)
+			limiter.Authenticator = auth.NewAPIKeys(map[string]auth.Principal{
+				"key-1": {Name: "shop", Role: auth.RoleEditor},
+				"key-2": {Name: "shop", Role: auth.RoleEditor},
+				"key-3": {Name: "blog", Role: auth.RoleEditor},
+			})
+			handler := limiter.Handler(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+				writer.WriteHeader(http.StatusNoContent)
+			}))
+
+			for i, key := range test.keys {
+				request := httptest.NewRequest(http.MethodGet, "/", nil)
+				request.RemoteAddr = "10.0.0.1:4000"
+				if key != "" {
+					request.Header.Set(auth.APIKeyHeader, key)
+				}
+				recorder := httptest.NewRecorder()
+				handler.ServeHTTP(recorder, request)
+				if recorder.Code != test.status[i] {
+					t.Errorf("request %d with key %q status = %d, want %d", i+1, key, recorder.Code, test.status[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		result Result
+		want   map[string]string
+	}{
+		{"allowed", Result{Allowed: true, Limit: 5, Remaining: 4, Reset: 1500 * time.Millisecond},
+			map[string]string{"RateLimit-Limit": "5", "RateLimit-Remaining": "4", "RateLimit-Reset": "2"}},
+		{"rejected", Result{Limit: 5, Reset: 10 * time.Second, RetryAfter: 100 * time.Millisecond},
+			map[string]string{"RateLimit-Limit": "5", "RateLimit-Remaining": "0", "RateLimit-Reset": "10", "Retry-After": "1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Headers(test.result)
+			if len(got) != len(test.want) {
+				t.Errorf("Headers() = %v, want %v", got, test.want)
+			}
+			for name, value := range test.want {
+				if got[name] != value {
+					t.Errorf("%s = %q, want %q", name, got[name], value)
+				}
+			}
+		})
+	}
+}
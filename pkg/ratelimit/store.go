@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps token buckets by key
+type Store interface {
+	// Take refills bucket of key by time passed and takes one token from it when there is one
+	Take(key string, limit Limit, now time.Time) Result
+}
+
+// MemoryStore keeps buckets in memory, buckets idle long enough to be full again are evicted
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// sweepInterval is how often MemoryStore looks for idle buckets
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+// Take implements Store
+func (m *MemoryStore) Take(key string, limit Limit, now time.Time) Result {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(limit, now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed.Seconds()*limit.Rate)
+		b.updated = now
+	}
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = limit.wait(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = limit.wait(float64(limit.Burst) - b.tokens)
+	return result
+}
+
+// sweep drops buckets which are full by now; must be called under lock
+func (m *MemoryStore) sweep(limit Limit, now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+	"github.com/MrHakimov/http/pkg/metrics"
+)
+
+// MetricsPath is where SetMetrics serves registry
+const MetricsPath = "/metrics"
+
+// unmatchedRoute labels requests whose path matches no registered route
+const unmatchedRoute = "unmatched"
+
+// serverMetrics instruments connections and requests, nil one records nothing
+type serverMetrics struct {
+	inFlight *metrics.Gauge
+	requests *metrics.Counter
+	duration *metrics.Histogram
+	bytesIn  *metrics.Counter
+	bytesOut *metrics.Counter
+}
+
+// SetMetrics makes server count connections, requests and read bytes in registry
+// and registers handler serving registry at MetricsPath
+func (s *Server) SetMetrics(registry *metrics.Registry) {
+	s.metrics = &serverMetrics{
+		inFlight: registry.NewGauge("server_connections_in_flight",
+			"Connections being handled."),
+		requests: registry.NewCounter("server_requests_total",
+			"Requests handled by method, registered route and status.", "method", "route", "status"),
+		duration: registry.NewHistogram("server_request_duration_seconds",
+			"Time spent in handlers.", metrics.DefaultBuckets, "method", "route", "status"),
+		bytesIn: registry.NewCounter("server_received_bytes_total",
+			"Bytes read from connections."),
+		bytesOut: registry.NewCounter("server_sent_bytes_total",
+			"Bytes written to connections."),
+	}
+	s.Register(MetricsPath, MetricsHandler(registry))
+}
+
+// MetricsHandler serves registry in text exposition format
+func MetricsHandler(registry *metrics.Registry) HandlerFunc {
+	return func(req *Request) {
+		var buf bytes.Buffer
+		if err := registry.Write(&buf); err != nil {
+			logging.Default().Error("write metrics", "error", err)
+		}
+		if err := req.WriteResponse(http.StatusOK, map[string]string{"Content-Type": metrics.ContentType}, buf.Bytes()); err != nil {
+			logging.Default().Warn("write response", "error", err)
+		}
+	}
+}
+
+func (m *serverMetrics) connectionOpened() {
+	if m != nil {
+		m.inFlight.Inc()
+	}
+}
+
+func (m *serverMetrics) connectionClosed() {
+	if m != nil {
+		m.inFlight.Dec()
+	}
+}
+
+func (m *serverMetrics) read(size int) {
+	if m != nil && size > 0 {
+		m.bytesIn.Add(float64(size))
+	}
+}
+
+// countWritten wraps conn, so bytes written by handlers are counted
+func (m *serverMetrics) countWritten(conn net.Conn) net.Conn {
+	if m == nil {
+		return conn
+	}
+	return &countingConn{Conn: conn, written: m.bytesOut}
+}
+
+type countingConn struct {
+	net.Conn
+	written *metrics.Counter
+}
+
+func (c *countingConn) Write(data []byte) (int, error) {
+	n, err := c.Conn.Write(data)
+	c.written.Add(float64(n))
+	return n, err
+}
+
+// handled records request served by route, which is registered pattern or empty when none matched;
+// status is 0 when handler wrote no response
+func (m *serverMetrics) handled(method string, route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = unmatchedRoute
+	}
+	m.requests.Inc(method, route, strconv.Itoa(status))
+	m.duration.Observe(duration.Seconds(), method, route, strconv.Itoa(status))
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/MrHakimov/http/pkg/metrics"
+)
+
+// serve sends raw request to server over new connection and returns response, nil when connection is closed without it;
+// it returns after server is done with connection, so request is recorded by then
+func serve(t *testing.T, s *Server, request string) (*http.Response, string) {
+	t.Helper()
+	client, conn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		s.handle(conn)
+		close(done)
+	}()
+	defer func() {
+		client.Close()
+		<-done
+	}()
+
+	if _, err := client.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.ReadResponse(bufio.NewReader(client), nil)
+	if err != nil {
+		return nil, ""
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response, string(body)
+}
+
+func TestMatchRoute(t *testing.T) {
+	tests := []struct {
+		route  string
+		path   string
+		params map[string]string
+		ok     bool
+	}{
+		{"/banners", "/banners", map[string]string{}, true},
+		{"/banners", "/other", nil, false},
+		{"/banners/{id}", "/banners/7", map[string]string{"id": "7"}, true},
+		{"/banners/{id}", "/banners", nil, false},
+		{"/banners/{id}", "/banners/7/image", nil, false},
+		{"/banners/id{id}", "/banners/id7", map[string]string{"id": "7"}, true},
+		{"/banners/id{id}", "/banners/7", nil, false},
+		{"/{kind}/{id}", "/banners/7", map[string]string{"kind": "banners", "id": "7"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.route+" "+test.path, func(t *testing.T) {
+			params, ok := matchRoute(test.route, test.path)
+			if ok != test.ok || len(params) != len(test.params) {
+				t.Fatalf("matchRoute() = %v, %v, want %v, %v", params, ok, test.params, test.ok)
+			}
+			for name, value := range test.params {
+				if params[name] != value {
+					t.Errorf("parameter %s = %q, want %q", name, params[name], value)
+				}
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := NewServer("")
+	s.Register("/banners/{id}", func(req *Request) {
+		status := http.StatusOK
+		if req.PathParams["id"] == "0" {
+			status = http.StatusNotFound
+		}
+		if err := req.WriteResponse(status, nil, []byte(req.PathParams["id"])); err != nil {
+			t.Error(err)
+		}
+	})
+	registry := metrics.NewRegistry()
+	s.SetMetrics(registry)
+
+	for _, request := range []string{
+		"GET /banners/7 HTTP/1.1\r\nHost: test\r\n\r\n",
+		"GET /banners/8 HTTP/1.1\r\nHost: test\r\n\r\n",
+		"GET /banners/0 HTTP/1.1\r\nHost: test\r\n\r\n",
+		"POST /missing HTTP/1.1\r\nHost: test\r\n\r\n",
+	} {
+		serve(t, s, request)
+	}
+
+	response, body := serve(t, s, "GET /metrics HTTP/1.1\r\nHost: test\r\n\r\n")
+	if response == nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("response of /metrics = %v", response)
+	}
+	if contentType := response.Header.Get("Content-Type"); contentType != metrics.ContentType {
+		t.Errorf("Content-Type = %q, want %q", contentType, metrics.ContentType)
+	}
+
+	tests := []string{
+		"# TYPE server_requests_total counter\n",
+		`server_requests_total{method="GET",route="/banners/{id}",status="200"} 2` + "\n",
+		`server_requests_total{method="GET",route="/banners/{id}",status="404"} 1` + "\n",
+		`server_requests_total{method="POST",route="unmatched",status="0"} 1` + "\n",
+		"# TYPE server_request_duration_seconds histogram\n",
+		`server_request_duration_seconds_bucket{method="GET",route="/banners/{id}",status="200",le="+Inf"} 2` + "\n",
+		`server_request_duration_seconds_count{method="GET",route="/banners/{id}",status="404"} 1` + "\n",
+	}
+	for _, want := range tests {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+}
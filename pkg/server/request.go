@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// Header returns value of header by case-insensitive name
+func (req *Request) Header(name string) string {
+	if value, ok := req.Headers[name]; ok {
+		return value
+	}
+	for key, value := range req.Headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// RemoteIP returns IP address of client connection
+func (req *Request) RemoteIP() string {
+	if req.Conn == nil {
+		return ""
+	}
+	addr := req.Conn.RemoteAddr().String()
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// Context returns context of request, background one when none is set
+func (req *Request) Context() context.Context {
+	if req.ctx == nil {
+		return context.Background()
+	}
+	return req.ctx
+}
+
+// WithContext returns shallow copy of request with ctx, middlewares pass it to next handler
+func (req *Request) WithContext(ctx context.Context) *Request {
+	clone := *req
+	clone.ctx = ctx
+	return &clone
+}
+
+// Status returns status written by WriteResponse to request or its copies, 0 when nothing is written yet
+func (req *Request) Status() int {
+	if req.written == nil {
+		return 0
+	}
+	return req.written.status
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteResponse writes response with status, headers and body to connection of request,
+// ResponseHeaders set by middlewares are written too unless headers override them
+func (req *Request) WriteResponse(status int, headers map[string]string, body []byte) error {
+	merged := make(map[string]string, len(req.ResponseHeaders)+len(headers))
+	for name, value := range req.ResponseHeaders {
+		merged[name] = value
+	}
+	for name, value := range headers {
+		merged[name] = value
+	}
+	headers = merged
+	if req.written == nil {
+		req.written = &written{}
+	}
+	req.written.status = status
+
+	var builder strings.Builder
+	builder.WriteString("HTTP/1.1 " + strconv.Itoa(status) + " " + http.StatusText(status) + "\r\n")
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		builder.WriteString(name + ": " + headers[name] + "\r\n")
+	}
+	if _, ok := headers["Content-Type"]; !ok && len(body) != 0 {
+		builder.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	}
+	builder.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n")
+	builder.WriteString("Connection: close\r\n\r\n")
+
+	_, err := req.Conn.Write(append([]byte(builder.String()), body...))
+	return err
+}
@@ -2,14 +2,18 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"strconv"
 	"strings"
 
-	"log"
 	"net"
 	"net/url"
+	"sort"
 	"sync"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
 )
 
 // HandlerFunc handler
@@ -22,15 +26,32 @@ type Server struct {
 	mu sync.RWMutex
 
 	handlers map[string]HandlerFunc
+
+	middlewares []func(HandlerFunc) HandlerFunc
+
+	metrics *serverMetrics
 }
 
 // Request class
 type Request struct {
 	Conn        net.Conn
+	Method      string
+	Path        string
 	QueryParams url.Values
 	PathParams  map[string]string
 	Headers     map[string]string
 	Body        []byte
+	// ResponseHeaders are set by middlewares and written by WriteResponse
+	ResponseHeaders map[string]string
+
+	ctx context.Context
+	// written is shared by copies made by WithContext
+	written *written
+}
+
+// written remembers status of response
+type written struct {
+	status int
 }
 
 // NewServer can create new servers
@@ -46,11 +67,18 @@ func (s *Server) Register(path string, handler HandlerFunc) {
 	s.mu.RUnlock()
 }
 
+// Use adds middleware wrapping every handler, middleware added last runs first
+func (s *Server) Use(middleware func(HandlerFunc) HandlerFunc) {
+	s.mu.Lock()
+	s.middlewares = append(s.middlewares, middleware)
+	s.mu.Unlock()
+}
+
 // Start is main function
 func (s *Server) Start() error {
 	listener, err := net.Listen("tcp", s.addr)
 	if err != nil {
-		log.Print(err)
+		logging.Default().Error("listen", "addr", s.addr, "error", err)
 		return err
 	}
 
@@ -61,14 +89,14 @@ func (s *Server) Start() error {
 				err = cerr
 				return
 			}
-			log.Print(cerr)
+			logging.Default().Error("close listener", "error", cerr)
 		}
 	}()
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			log.Print(err)
+			logging.Default().Error("accept connection", "error", err)
 			continue
 		}
 
@@ -78,21 +106,26 @@ func (s *Server) Start() error {
 
 func (s *Server) handle(conn net.Conn) {
 	defer conn.Close()
+	s.metrics.connectionOpened()
+	defer s.metrics.connectionClosed()
+	conn = s.metrics.countWritten(conn)
 
 	buf := make([]byte, (1024 * 8))
 	for {
 		bufferSize, err := conn.Read(buf)
 		if err == io.EOF {
-			log.Printf("%s", buf[:bufferSize])
+			logging.Default().Debug("connection closed", "remote", conn.RemoteAddr(), "unread", bufferSize)
+			return
 		}
 
 		if err != nil {
-			log.Println(err)
+			logging.Default().Warn("read request", "remote", conn.RemoteAddr(), "error", err)
 			return
 		}
 
 		var req Request
 		data := buf[:bufferSize]
+		s.metrics.read(bufferSize)
 
 		endOfLine := []byte{'\r', '\n'}
 		endIndex := bytes.Index(data, endOfLine)
@@ -137,81 +170,83 @@ func (s *Server) handle(conn net.Conn) {
 
 		decode, err := url.PathUnescape(path)
 		if err != nil {
-			log.Println(err)
+			logging.Default().Debug("invalid request path", "path", path, "error", err)
 			return
 		}
 
 		uri, err := url.ParseRequestURI(decode)
 		if err != nil {
-			log.Println(err)
+			logging.Default().Debug("invalid request path", "path", path, "error", err)
 			return
 		}
 
 		req.Conn = conn
+		req.ResponseHeaders = make(map[string]string)
+		req.written = &written{}
+		req.Method = parts[0]
+		req.Path = uri.Path
 		req.QueryParams = uri.Query()
 
 		var handler = func(req *Request) { conn.Close() }
 
 		s.mu.RLock()
-		pathParameters, hr := s.validate(uri.Path)
+		pathParameters, route, hr := s.validate(uri.Path)
 		if hr != nil {
 			handler = hr
 			req.PathParams = pathParameters
 		}
+		for _, middleware := range s.middlewares {
+			handler = middleware(handler)
+		}
 		s.mu.RUnlock()
 
+		started := time.Now()
 		handler(&req)
+		s.metrics.handled(req.Method, route, req.Status(), time.Since(started))
 	}
 }
 
-func (s *Server) validate(path string) (map[string]string, HandlerFunc) {
-	strRoutes := make([]string, len(s.handlers))
-	i := 0
-	for k := range s.handlers {
-		strRoutes[i] = k
-		i++
+// validate returns path parameters, registered route matching path and its handler;
+// route equal to path wins, other routes are tried in sorted order
+func (s *Server) validate(path string) (map[string]string, string, HandlerFunc) {
+	if hr, found := s.handlers[path]; found {
+		return map[string]string{}, path, hr
 	}
 
-	headerTo := make(map[string]string)
-
-	for i := 0; i < len(strRoutes); i++ {
-		flag := false
-		route := strRoutes[i]
-		partsRoute := strings.Split(route, "/")
-		pRotes := strings.Split(path, "/")
-
-		for j, v := range partsRoute {
-			if v != "" {
-				f := v[0:1]
-				l := v[len(v)-1:]
-				if f == "{" && l == "}" {
-					headerTo[v[1:len(v)-1]] = pRotes[j]
-					flag = true
-				} else if pRotes[j] != v {
-
-					strs := strings.Split(v, "{")
-					if len(strs) > 0 {
-						key := strs[1][:len(strs[1])-1]
-						headerTo[key] = pRotes[j][len(strs[0]):]
-						flag = true
-					} else {
-						flag = false
-						break
-					}
-				}
-				flag = true
-			}
-		}
-		if flag {
-			if hr, found := s.handlers[route]; found {
-				return headerTo, hr
-			}
-			break
+	routes := make([]string, 0, len(s.handlers))
+	for route := range s.handlers {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		if params, ok := matchRoute(route, path); ok {
+			return params, route, s.handlers[route]
 		}
 	}
+	return nil, "", nil
+}
 
-	return nil, nil
+// matchRoute matches path by segments, segment of route like {id} or id{id} takes rest of path segment
+// after prefix as parameter, other segments must be equal
+func matchRoute(route string, path string) (map[string]string, bool) {
+	routeParts := strings.Split(route, "/")
+	pathParts := strings.Split(path, "/")
+	if len(routeParts) != len(pathParts) {
+		return nil, false
+	}
 
+	params := make(map[string]string)
+	for i, part := range routeParts {
+		if part == pathParts[i] {
+			continue
+		}
+		open := strings.Index(part, "{")
+		if open == -1 || !strings.HasSuffix(part, "}") || !strings.HasPrefix(pathParts[i], part[:open]) {
+			return nil, false
+		}
+		params[part[open+1:len(part)-1]] = pathParts[i][open:]
+	}
+	return params, true
 }
 
 // Response common answer
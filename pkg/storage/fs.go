@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps blobs as files inside directory
+type FileStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileStore creates store in dir, blobs are served by baseURL
+func NewFileStore(dir string, baseURL string) *FileStore {
+	return &FileStore{dir: dir, baseURL: baseURL}
+}
+
+// Dir returns root directory of store
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+// Put writes blob to temporary file and renames it,
+// so readers never see partially written data
+func (s *FileStore) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(name)
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get opens file of blob
+func (s *FileStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Delete removes file of blob
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL of blob
+func (s *FileStore) URL(key string) string {
+	return joinURL(s.baseURL, key)
+}
+
+func (s *FileStore) path(key string) (string, error) {
+	cleaned, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+type memoryBlob struct {
+	data        []byte
+	contentType string
+}
+
+// MemoryStore keeps blobs in memory, useful for tests and development
+type MemoryStore struct {
+	mu      sync.RWMutex
+	blobs   map[string]memoryBlob
+	baseURL string
+}
+
+// NewMemoryStore creates empty store
+func NewMemoryStore(baseURL string) *MemoryStore {
+	return &MemoryStore{blobs: make(map[string]memoryBlob), baseURL: baseURL}
+}
+
+// Put copies data into memory
+func (s *MemoryStore) Put(ctx context.Context, key string, data io.Reader, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	content, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[key] = memoryBlob{data: content, contentType: contentType}
+	return nil
+}
+
+// Get returns reader over stored copy
+func (s *MemoryStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	blob, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ioutil.NopCloser(bytes.NewReader(blob.data)), nil
+}
+
+// Delete forgets blob
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+// URL of blob
+func (s *MemoryStore) URL(key string) string {
+	return joinURL(s.baseURL, key)
+}
+
+// Keys returns keys of all stored blobs
+func (s *MemoryStore) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.blobs))
+	for key := range s.blobs {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound is returned when blob by key does not exist
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey is returned for empty keys and keys escaping store root
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// BlobStore keeps binary objects (banner images and so on) by key
+type BlobStore interface {
+	// Put stores data under key, replacing previous blob
+	Put(ctx context.Context, key string, data io.Reader, contentType string) error
+	// Get opens blob by key, caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes blob by key, missing blob is not an error
+	Delete(ctx context.Context, key string) error
+	// URL returns address where blob can be downloaded from
+	URL(key string) string
+}
+
+// cleanKey normalizes key and rejects keys like "../x" or "/etc/passwd"
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
+
+func joinURL(base string, key string) string {
+	if base == "" {
+		return key
+	}
+	return strings.TrimSuffix(base, "/") + "/" + key
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// s3StandIn is in-memory bucket answering path-style S3 requests, it rejects unsigned ones
+type s3StandIn struct {
+	t      *testing.T
+	bucket string
+	mu     sync.Mutex
+	blobs  map[string][]byte
+}
+
+func (b *s3StandIn) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := ioutil.ReadAll(request.Body)
+	auth := request.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=access/20260101/eu-central-1/s3/aws4_request,") ||
+		request.Header.Get("X-Amz-Date") != "20260101T120000Z" ||
+		request.Header.Get("X-Amz-Content-Sha256") != sha256Hex(body) {
+		b.t.Errorf("%s %s is not signed: %q", request.Method, request.URL.Path, auth)
+		http.Error(writer, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+	prefix := "/" + b.bucket + "/"
+	if !strings.HasPrefix(request.URL.Path, prefix) {
+		http.Error(writer, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(request.URL.Path, prefix)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch request.Method {
+	case http.MethodPut:
+		b.blobs[key] = body
+	case http.MethodGet:
+		blob, ok := b.blobs[key]
+		if !ok {
+			http.Error(writer, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		writer.Write(blob)
+	case http.MethodDelete:
+		delete(b.blobs, key)
+		writer.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(writer, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func TestBlobStores(t *testing.T) {
+	bucket := &s3StandIn{t: t, bucket: "banners", blobs: make(map[string][]byte)}
+	server := httptest.NewServer(bucket)
+	defer server.Close()
+	s3 := NewS3Store(S3Config{
+		Endpoint: server.URL, Region: "eu-central-1", Bucket: "banners",
+		AccessKey: "access", SecretKey: "secret", Client: server.Client(),
+	})
+	s3.now = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+
+	dir, err := ioutil.TempDir("", "blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stores := []struct {
+		name  string
+		store BlobStore
+		url   string
+	}{
+		{"memory", NewMemoryStore("http://cdn/"), "http://cdn/images/1-ab.png"},
+		{"file", NewFileStore(dir, "/media"), "/media/images/1-ab.png"},
+		{"s3", s3, server.URL + "/banners/images/1-ab.png"},
+	}
+
+	for _, test := range stores {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			key := "images/1-ab.png"
+			if err := test.store.Put(ctx, key, bytes.NewReader([]byte("first")), "image/png"); err != nil {
+				t.Fatalf("Put() = %v", err)
+			}
+			if err := test.store.Put(ctx, key, bytes.NewReader([]byte("second")), "image/png"); err != nil {
+				t.Fatalf("Put() again = %v", err)
+			}
+			if got := read(t, test.store, key); got != "second" {
+				t.Errorf("Get() = %q, want replaced blob", got)
+			}
+			if got := test.store.URL(key); got != test.url {
+				t.Errorf("URL() = %q, want %q", got, test.url)
+			}
+
+			if err := test.store.Delete(ctx, key); err != nil {
+				t.Fatalf("Delete() = %v", err)
+			}
+			if err := test.store.Delete(ctx, key); err != nil {
+				t.Errorf("Delete() of missing blob = %v", err)
+			}
+			if _, err := test.store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of deleted blob = %v, want ErrNotFound", err)
+			}
+			for _, bad := range []string{"", "/etc/passwd", "../x", "a\\b"} {
+				if err := test.store.Put(ctx, bad, bytes.NewReader(nil), ""); !errors.Is(err, ErrInvalidKey) {
+					t.Errorf("Put(%q) = %v, want ErrInvalidKey", bad, err)
+				}
+			}
+		})
+	}
+}
+
+func TestS3StoreError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, "AccessDenied", http.StatusForbidden)
+	}))
+	defer server.Close()
+	store := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "banners", Client: server.Client()})
+
+	err := store.Put(context.Background(), "1.png", bytes.NewReader([]byte("x")), "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Put() = %v, want error with status and message", err)
+	}
+}
+
+func read(t *testing.T, store BlobStore, key string) string {
+	t.Helper()
+	blob, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q) = %v", key, err)
+	}
+	defer blob.Close()
+	data, err := ioutil.ReadAll(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
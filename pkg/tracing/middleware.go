@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/MrHakimov/http/pkg/server"
+)
+
+// Handler traces requests to next, span of caller given by traceparent header becomes parent.
+// Span is named by method and path, next may rename it by route.
+func (t *Tracer) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ctx := request.Context()
+		if remote, ok := Extract(request.Header); ok {
+			ctx = ContextWithRemote(ctx, remote)
+		}
+		ctx, span := t.Start(ctx, request.Method+" "+request.URL.Path, KindServer)
+		defer span.End()
+		span.SetAttribute("http.method", request.Method)
+		span.SetAttribute("http.target", request.URL.RequestURI())
+		span.SetAttribute("net.peer.addr", request.RemoteAddr)
+
+		recorder := &statusRecorder{ResponseWriter: writer}
+		next.ServeHTTP(recorder, request.WithContext(ctx))
+		setStatus(span, recorder.Status())
+	})
+}
+
+// HandlerFunc traces requests to next handler of pkg/server like Handler does
+func (t *Tracer) HandlerFunc(next server.HandlerFunc) server.HandlerFunc {
+	return func(req *server.Request) {
+		ctx := req.Context()
+		if remote, ok := ParseTraceparent(req.Header(TraceparentHeader)); ok {
+			remote.TraceState = ParseTracestate(req.Header(TracestateHeader))
+			ctx = ContextWithRemote(ctx, remote)
+		}
+		ctx, span := t.Start(ctx, req.Method+" "+req.Path, KindServer)
+		defer span.End()
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.target", req.Path)
+		span.SetAttribute("net.peer.addr", req.RemoteIP())
+
+		traced := req.WithContext(ctx)
+		next(traced)
+		setStatus(span, traced.Status())
+	}
+}
+
+// setStatus records status code, 5xx marks span failed
+func setStatus(span *Span, status int) {
+	if status == 0 {
+		return
+	}
+	span.SetAttribute("http.status_code", status)
+	if status >= http.StatusInternalServerError {
+		span.SetError(errors.New(http.StatusText(status)))
+	}
+}
+
+// statusRecorder remembers status written by handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Status returns written status, 200 when handler wrote nothing
+func (r *statusRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// scopeName is instrumentation scope reported with spans
+const scopeName = "github.com/MrHakimov/http/pkg/tracing"
+
+// OTLPExporter posts spans to OTLP/HTTP collector encoded as JSON
+type OTLPExporter struct {
+	endpoint string
+	service  string
+	// Headers are added to every export request, e.g. authorization of collector
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// NewOTLPExporter creates exporter of service posting to endpoint like http://localhost:4318/v1/traces
+func NewOTLPExporter(endpoint string, service string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: endpoint,
+		service:  service,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Export posts spans in one request, collector must answer 2xx
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(e.service, spans))
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range e.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := e.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 1024))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("otlp export: %s: %s", response.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// WriterExporter writes every span as OTLP JSON line to writer, e.g. to stdout while debugging
+type WriterExporter struct {
+	writer  io.Writer
+	service string
+}
+
+// NewWriterExporter creates exporter of service writing to writer
+func NewWriterExporter(writer io.Writer, service string) *WriterExporter {
+	return &WriterExporter{writer: writer, service: service}
+}
+
+// Export writes one line with all spans
+func (e *WriterExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(otlpRequest(e.service, spans))
+	if err != nil {
+		return err
+	}
+	_, err = e.writer.Write(append(body, '\n'))
+	return err
+}
+
+// OTLP JSON encoding, ids are hex strings and 64-bit integers are decimal strings
+
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	TraceState        string          `json:"traceState,omitempty"`
+	Flags             uint32          `json:"flags"`
+	Name              string          `json:"name"`
+	Kind              Kind            `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// otlpStatusError is OTLP status code of failed span, status of other spans is left unset
+const otlpStatusError = 2
+
+func otlpRequest(service string, spans []SpanData) otlpExportRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		item := otlpSpan{
+			TraceID:           span.Context.TraceID.String(),
+			SpanID:            span.Context.SpanID.String(),
+			TraceState:        span.Context.TraceState,
+			Flags:             uint32(span.Context.Flags),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if span.Parent.IsValid() {
+			item.ParentSpanID = span.Parent.String()
+		}
+		if span.Error {
+			item.Status = otlpStatus{Code: otlpStatusError, Message: span.ErrorMessage}
+		}
+		encoded = append(encoded, item)
+	}
+
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: otlpAttributes(map[string]interface{}{"service.name": service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: scopeName}, Spans: encoded}},
+	}}}
+}
+
+// otlpAttributes encodes attributes sorted by key, unsupported values are written as strings
+func otlpAttributes(attributes map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		var value otlpValue
+		switch v := attributes[key].(type) {
+		case string:
+			value.StringValue = &v
+		case bool:
+			value.BoolValue = &v
+		case int:
+			text := strconv.Itoa(v)
+			value.IntValue = &text
+		case int64:
+			text := strconv.FormatInt(v, 10)
+			value.IntValue = &text
+		case float64:
+			value.DoubleValue = &v
+		default:
+			text := fmt.Sprint(v)
+			value.StringValue = &text
+		}
+		result = append(result, otlpAttribute{Key: key, Value: value})
+	}
+	return result
+}
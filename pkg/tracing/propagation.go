@@ -0,0 +1,194 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTracestateMembers is limit of list members given by W3C Trace Context
+const maxTracestateMembers = 32
+
+// TraceID identifies trace, it is all zeros when invalid
+type TraceID [16]byte
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether id is not all zeros
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID identifies span inside trace, it is all zeros when invalid
+type SpanID [8]byte
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether id is not all zeros
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// FlagSampled is trace flag telling that caller records trace
+const FlagSampled byte = 0x01
+
+// SpanContext is part of span propagated to other services
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	// Remote is true for span context received from caller
+	Remote bool
+}
+
+// IsValid reports whether trace and span ids are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// IsSampled reports whether sampled flag is set
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+// Traceparent formats span context as value of traceparent header
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// ParseTraceparent parses traceparent header of version 00, higher versions are parsed
+// by their 00 prefix as W3C Trace Context requires
+func ParseTraceparent(value string) (SpanContext, bool) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 {
+		return SpanContext{}, false
+	}
+	version, err := hex.DecodeString(value[:2])
+	if err != nil || version[0] == 0xff || value[:2] != strings.ToLower(value[:2]) {
+		return SpanContext{}, false
+	}
+	if version[0] == 0 && len(value) != 55 {
+		return SpanContext{}, false
+	}
+	if len(value) > 55 && value[55] != '-' {
+		return SpanContext{}, false
+	}
+	if value[2] != '-' || value[35] != '-' || value[52] != '-' {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	if !decodeLowerHex(sc.TraceID[:], value[3:35]) || !decodeLowerHex(sc.SpanID[:], value[36:52]) {
+		return SpanContext{}, false
+	}
+	flags := make([]byte, 1)
+	if !decodeLowerHex(flags, value[53:55]) {
+		return SpanContext{}, false
+	}
+	sc.Flags = flags[0]
+	sc.Remote = true
+	if !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	return sc, true
+}
+
+func decodeLowerHex(dst []byte, src string) bool {
+	if src != strings.ToLower(src) {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(src))
+	return err == nil
+}
+
+// ParseTracestate returns tracestate header normalized to comma separated members,
+// empty string when header is malformed so it is dropped instead of propagated
+func ParseTracestate(value string) string {
+	members := make([]string, 0)
+	keys := make(map[string]bool)
+	for _, member := range strings.Split(value, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		eq := strings.IndexByte(member, '=')
+		if eq <= 0 || eq == len(member)-1 {
+			return ""
+		}
+		key, val := member[:eq], member[eq+1:]
+		if !validTracestateKey(key) || !validTracestateValue(val) || keys[key] {
+			return ""
+		}
+		keys[key] = true
+		members = append(members, member)
+	}
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+// validTracestateKey allows lowercase letters, digits, _-*/ and single @ of multi-tenant keys
+func validTracestateKey(key string) bool {
+	if len(key) > 256 || strings.Count(key, "@") > 1 {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case i > 0 && (c == '_' || c == '-' || c == '*' || c == '/' || c == '@'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func validTracestateValue(value string) bool {
+	if len(value) > 256 || strings.HasSuffix(value, " ") {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if c := value[i]; c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract returns span context of caller given by headers
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, ok := ParseTraceparent(header.Get(TraceparentHeader))
+	if !ok {
+		return SpanContext{}, false
+	}
+	sc.TraceState = ParseTracestate(strings.Join(header.Values(TracestateHeader), ","))
+	return sc, true
+}
+
+// Inject sets traceparent and tracestate of span in ctx to headers of outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanFromContext(ctx).Context()
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	} else {
+		header.Del(TracestateHeader)
+	}
+}
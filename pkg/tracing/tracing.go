@@ -0,0 +1,277 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MrHakimov/http/pkg/logging"
+)
+
+// MaxQueue limits ended spans waiting for export, spans above it are dropped
+const MaxQueue = 4096
+
+// flushTimeout limits export of remaining spans when Run stops
+const flushTimeout = 5 * time.Second
+
+// Kind tells role of span in request
+type Kind int
+
+// Span kinds, values match OTLP
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+)
+
+// SpanData is finished span given to exporter
+type SpanData struct {
+	Name         string
+	Context      SpanContext
+	Parent       SpanID
+	Kind         Kind
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]interface{}
+	Error        bool
+	ErrorMessage string
+}
+
+// Exporter sends finished spans to tracing backend
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+}
+
+// Tracer creates spans of service and exports them in batches by Run or Flush
+type Tracer struct {
+	service  string
+	exporter Exporter
+
+	mu      sync.Mutex
+	queue   []SpanData
+	dropped int64
+}
+
+// NewTracer creates tracer of service, spans are exported to exporter
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+// Service returns name of traced service
+func (t *Tracer) Service() string {
+	return t.service
+}
+
+// Start creates span with parent from ctx, remote parent given by ContextWithRemote
+// or, when there is none, root span of new trace
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx).Context()
+	if !parent.IsValid() {
+		parent = remoteFromContext(ctx)
+	}
+
+	span := &Span{tracer: t, data: SpanData{Name: name, Kind: kind, Start: time.Now()}}
+	if parent.IsValid() {
+		span.data.Context.TraceID = parent.TraceID
+		span.data.Context.Flags = parent.Flags
+		span.data.Context.TraceState = parent.TraceState
+		span.data.Parent = parent.SpanID
+	} else {
+		span.data.Context.TraceID = newTraceID()
+		span.data.Context.Flags = FlagSampled
+	}
+	span.data.Context.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+// Start creates child of span in ctx, it returns nil span, which does nothing,
+// when ctx is not traced so code may be instrumented regardless of tracer
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, KindInternal)
+}
+
+// end queues sampled span for export
+func (t *Tracer) end(data SpanData) {
+	if !data.Context.IsSampled() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.queue) >= MaxQueue {
+		t.dropped++
+		return
+	}
+	t.queue = append(t.queue, data)
+}
+
+// Dropped returns number of spans dropped because queue was full
+func (t *Tracer) Dropped() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// Flush exports queued spans, they are dropped when export fails
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	spans := t.queue
+	t.queue = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 || t.exporter == nil {
+		return nil
+	}
+	return t.exporter.Export(ctx, spans)
+}
+
+// Run flushes spans every interval until ctx is done, then flushes the rest
+func (t *Tracer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := t.Flush(ctx); err != nil {
+				logging.FromContext(ctx).Error("export spans", "error", err)
+			}
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+			if err := t.Flush(flushCtx); err != nil {
+				logging.FromContext(ctx).Error("export spans", "error", err)
+			}
+			cancel()
+			return
+		}
+	}
+}
+
+// Span is operation being traced, all methods of nil span do nothing
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Context returns span context to propagate, zero one for nil span
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.Context
+}
+
+// SetName renames span, e.g. after route of request is known
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute sets string, bool, integer or float attribute
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]interface{})
+	}
+	s.data.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetError marks span failed when err is not nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Error = true
+	s.data.ErrorMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes span, second call does nothing
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	attributes := make(map[string]interface{}, len(s.data.Attributes))
+	for key, value := range s.data.Attributes {
+		attributes[key] = value
+	}
+	data.Attributes = attributes
+	s.mu.Unlock()
+
+	s.tracer.end(data)
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// ContextWithSpan returns copy of ctx carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns span of ctx or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemote returns copy of ctx carrying span context received from caller,
+// it becomes parent of span started by Tracer.Start
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+func remoteFromContext(ctx context.Context) SpanContext {
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+var errRandom = errors.New("tracing: random source failed")
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		randomBytes(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		randomBytes(id[:])
+	}
+	return id
+}
+
+func randomBytes(buf []byte) {
+	if _, err := rand.Read(buf); err != nil {
+		panic(errRandom)
+	}
+}
@@ -0,0 +1,172 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name    string
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true, false},
+		{"future version with suffix", "01-" + traceID + "-" + spanID + "-01-extra", true, true},
+		{"version 00 with suffix", "00-" + traceID + "-" + spanID + "-01-extra", false, false},
+		{"version ff", "ff-" + traceID + "-" + spanID + "-01", false, false},
+		{"upper case", "00-" + strings.ToUpper(traceID) + "-" + spanID + "-01", false, false},
+		{"zero trace id", "00-" + strings.Repeat("0", 32) + "-" + spanID + "-01", false, false},
+		{"zero span id", "00-" + traceID + "-" + strings.Repeat("0", 16) + "-01", false, false},
+		{"short", "00-" + traceID + "-01", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(test.value)
+			if ok != test.valid {
+				t.Fatalf("ParseTraceparent() ok = %v, want %v", ok, test.valid)
+			}
+			if !ok {
+				return
+			}
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID || sc.IsSampled() != test.sampled {
+				t.Errorf("ParseTraceparent() = %+v", sc)
+			}
+		})
+	}
+}
+
+// collectorStub is OTLP/HTTP collector remembering received export requests
+type collectorStub struct {
+	mu       sync.Mutex
+	status   int
+	requests []otlpExportRequest
+	headers  []http.Header
+}
+
+func (c *collectorStub) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	body, _ := ioutil.ReadAll(request.Body)
+	var export otlpExportRequest
+	if request.Method != http.MethodPost || json.Unmarshal(body, &export) != nil {
+		http.Error(writer, "bad export request", http.StatusBadRequest)
+		return
+	}
+	c.requests = append(c.requests, export)
+	c.headers = append(c.headers, request.Header)
+	if c.status != 0 {
+		http.Error(writer, "collector is overloaded", c.status)
+	}
+}
+
+func TestExportToCollector(t *testing.T) {
+	const remote = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name        string
+		traceparent string
+		status      int
+		// spans are names of exported spans, server span goes last as it ends last
+		spans []string
+	}{
+		{"new trace", "", http.StatusOK, []string{"banners.save", "GET /banners"}},
+		{"remote parent", remote, http.StatusOK, []string{"banners.save", "GET /banners"}},
+		{"failed request", "", http.StatusInternalServerError, []string{"banners.save", "GET /banners"}},
+		{"not sampled", strings.TrimSuffix(remote, "01") + "00", http.StatusOK, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			collector := &collectorStub{}
+			server := httptest.NewServer(collector)
+			defer server.Close()
+			exporter := NewOTLPExporter(server.URL+"/v1/traces", "banners")
+			exporter.Headers = map[string]string{"Authorization": "Bearer collector-token"}
+			tracer := NewTracer("banners", exporter)
+
+			handler := tracer.Handler(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				_, span := Start(request.Context(), "banners.save")
+				span.SetAttribute("banner.id", int64(7))
+				span.End()
+				writer.WriteHeader(test.status)
+			}))
+			request := httptest.NewRequest(http.MethodGet, "/banners", nil)
+			if test.traceparent != "" {
+				request.Header.Set(TraceparentHeader, test.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), request)
+			if err := tracer.Flush(context.Background()); err != nil {
+				t.Fatalf("Flush() = %v", err)
+			}
+
+			if test.spans == nil {
+				if len(collector.requests) != 0 {
+					t.Errorf("%d export requests, want none", len(collector.requests))
+				}
+				return
+			}
+			if len(collector.requests) != 1 {
+				t.Fatalf("%d export requests, want 1", len(collector.requests))
+			}
+			if got := collector.headers[0].Get("Authorization"); got != "Bearer collector-token" {
+				t.Errorf("Authorization = %q", got)
+			}
+			resource := collector.requests[0].ResourceSpans[0]
+			if attr := resource.Resource.Attributes[0]; attr.Key != "service.name" || *attr.Value.StringValue != "banners" {
+				t.Errorf("resource attribute = %+v, want service.name", attr)
+			}
+			spans := resource.ScopeSpans[0].Spans
+			var names []string
+			for _, span := range spans {
+				names = append(names, span.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(test.spans, ",") {
+				t.Fatalf("spans = %v, want %v", names, test.spans)
+			}
+
+			child, root := spans[0], spans[1]
+			if child.TraceID != root.TraceID || child.ParentSpanID != root.SpanID {
+				t.Errorf("child %s/%s is not in trace of server span %s/%s", child.TraceID, child.ParentSpanID, root.TraceID, root.SpanID)
+			}
+			if test.traceparent != "" && (root.TraceID != test.traceparent[3:35] || root.ParentSpanID != test.traceparent[36:52]) {
+				t.Errorf("server span %s/%s does not continue %s", root.TraceID, root.ParentSpanID, test.traceparent)
+			}
+			failed := root.Status.Code == otlpStatusError
+			if failed != (test.status >= 500) {
+				t.Errorf("server span status = %+v for response %d", root.Status, test.status)
+			}
+		})
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	collector := &collectorStub{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+	tracer := NewTracer("banners", NewOTLPExporter(server.URL, "banners"))
+
+	_, span := tracer.Start(context.Background(), "job", KindInternal)
+	span.End()
+	err := tracer.Flush(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "collector is overloaded") {
+		t.Errorf("Flush() = %v, want error with status and message of collector", err)
+	}
+	if err := tracer.Flush(context.Background()); err != nil || len(collector.requests) != 1 {
+		t.Errorf("second Flush() = %v after %d requests, want failed spans dropped", err, len(collector.requests))
+	}
+}